@@ -0,0 +1,349 @@
+// Package client is typed Go client for merch store API.
+//
+// Client logs in lazily and logs in again when token is rejected, so it only needs credentials:
+//
+//	c := client.New("http://localhost:8080", "alice", "secret")
+//	if err := c.SendCoin(ctx, "bob", 10); errors.Is(err, client.ErrInsufficientFunds) {
+//		...
+//	}
+//
+// Failed requests are retried, POST requests carry Idempotency-Key that stays the same between retries,
+// so coins are not sent twice when response is lost.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"merch_store/internal/idempotency"
+	"merch_store/internal/models"
+)
+
+// Default retry policy, delay doubles after every failed attempt.
+const (
+	DefaultMaxAttempts = 3
+	DefaultRetryDelay  = 100 * time.Millisecond
+)
+
+// Client calls merch store API on behalf of one user, it is safe for concurrent use...
+type Client struct {
+	baseURL     string
+	username    string
+	password    string
+	httpClient  *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+
+	mu    sync.Mutex
+	token string
+}
+
+// Option configures Client...
+type Option func(*Client)
+
+// WithHTTPClient sets HTTP client used for requests, http.DefaultClient is used by default...
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times request is attempted and delay before the first retry...
+func WithRetries(maxAttempts int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.retryDelay = delay
+	}
+}
+
+// WithToken sets token obtained earlier, client logs in again when it expires unless password is empty...
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New creates client for server at baseURL acting as user with given credentials,
+// user is registered by server on first login...
+func New(baseURL, username, password string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		username:    username,
+		password:    password,
+		httpClient:  http.DefaultClient,
+		maxAttempts: DefaultMaxAttempts,
+		retryDelay:  DefaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns current token, it is empty until client logs in...
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Login gets new token for client credentials...
+func (c *Client) Login(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.login(ctx)
+}
+
+// login must be called with c.mu held...
+func (c *Client) login(ctx context.Context) (string, error) {
+	var response models.AuthResponse
+	err := c.send(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/auth",
+		body:   models.AuthRequest{Username: c.username, Password: c.password},
+		out:    &response,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	c.token = response.Token
+	return c.token, nil
+}
+
+// currentToken returns token, logging in when there is none or when stale token was rejected...
+func (c *Client) currentToken(ctx context.Context, rejected string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.token != rejected {
+		return c.token, nil
+	}
+	if c.password == "" {
+		return "", fmt.Errorf("%w: token is missing or expired and client has no password to log in", ErrUnauthorized)
+	}
+	return c.login(ctx)
+}
+
+// Info returns balance, inventory, coin history and orders of user...
+func (c *Client) Info(ctx context.Context) (*InfoResponse, error) {
+	var response InfoResponse
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/api/info", out: &response}); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// SendCoin sends amount of coins to user toUser...
+func (c *Client) SendCoin(ctx context.Context, toUser string, amount Coins) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/sendCoin",
+		body:   models.SendCoinRequest{ToUser: toUser, Amount: amount},
+	})
+}
+
+// Catalog returns merch available in store...
+func (c *Client) Catalog(ctx context.Context) (*CatalogResponse, error) {
+	var response CatalogResponse
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/api/merch", out: &response}); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Buy buys one merch item, merch with variants is bought as its first variant...
+func (c *Client) Buy(ctx context.Context, item string) error {
+	return c.BuyVariant(ctx, item, VariantOptions{})
+}
+
+// BuyVariant buys one merch item of variant with given size and color...
+func (c *Client) BuyVariant(ctx context.Context, item string, options VariantOptions) error {
+	return c.buy(ctx, item, options, url.Values{})
+}
+
+// Gift buys one merch item for another user, options select variant of merch that has them...
+func (c *Client) Gift(ctx context.Context, to, item string, options VariantOptions) error {
+	return c.buy(ctx, item, options, url.Values{"to": {to}})
+}
+
+func (c *Client) buy(ctx context.Context, item string, options VariantOptions, query url.Values) error {
+	path := "/api/buy/" + url.PathEscape(item)
+	if options.Size != "" {
+		query.Set("size", options.Size)
+	}
+	if options.Color != "" {
+		query.Set("color", options.Color)
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return c.do(ctx, request{method: http.MethodPost, path: path})
+}
+
+// Orders returns orders of all users in status, or in any status when it's empty, user must be store manager...
+func (c *Client) Orders(ctx context.Context, status OrderStatus) (*OrdersResponse, error) {
+	path := "/api/orders"
+	if status != "" {
+		path += "?" + url.Values{"status": {string(status)}}.Encode()
+	}
+
+	var response OrdersResponse
+	if err := c.do(ctx, request{method: http.MethodGet, path: path, out: &response}); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// SetOrderStatus moves order to status, user must be store manager...
+func (c *Client) SetOrderStatus(ctx context.Context, id int, status OrderStatus) (*Order, error) {
+	var order Order
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/api/orders/%d/status", id),
+		body:   models.OrderStatusRequest{Status: status},
+		out:    &order,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelOrder cancels order of user that is not handed over yet, its price is returned to balance...
+func (c *Client) CancelOrder(ctx context.Context, id int) (*Order, error) {
+	var order Order
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/api/orders/%d/cancel", id),
+		out:    &order,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+type request struct {
+	method string
+	path   string
+	body   any
+	out    any
+	token  string
+}
+
+// do sends authorized request, it logs in again once if token is rejected...
+func (c *Client) do(ctx context.Context, req request) error {
+	token, err := c.currentToken(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	req.token = token
+	err = c.send(ctx, req)
+	if !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	if req.token, err = c.currentToken(ctx, token); err != nil {
+		return err
+	}
+	return c.send(ctx, req)
+}
+
+// send sends request retrying network errors, 5xx and 409 responses...
+func (c *Client) send(ctx context.Context, req request) error {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return err
+		}
+	}
+
+	key := ""
+	if req.method == http.MethodPost {
+		key = newIdempotencyKey()
+	}
+
+	delay := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		status, body, err := c.roundTrip(ctx, req, payload, key)
+		if err == nil && status < http.StatusBadRequest {
+			if req.out == nil {
+				return nil
+			}
+			return json.Unmarshal(body, req.out)
+		}
+		if err == nil {
+			err = newAPIError(status, body)
+		}
+
+		if attempt >= c.maxAttempts || !retryable(status, err) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, req request, payload []byte, key string) (int, []byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.token != "" {
+		httpReq.Header.Set("Authorization", req.token)
+	}
+	if key != "" {
+		httpReq.Header.Set(idempotency.Header, key)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s %s: %w", req.method, req.path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s %s: read response: %w", req.method, req.path, err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// retryable reports whether request may succeed if sent again, status is 0 for network errors...
+func retryable(status int, err error) bool {
+	if status == 0 {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return status == http.StatusConflict || status >= http.StatusInternalServerError
+}
+
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("generate idempotency key: %v", err))
+	}
+	return hex.EncodeToString(key)
+}
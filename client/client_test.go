@@ -0,0 +1,275 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"merch_store/client"
+	"merch_store/internal/db"
+	"merch_store/internal/handlers"
+	"merch_store/internal/idempotency"
+	"merch_store/internal/models"
+	"merch_store/internal/seed"
+	"merch_store/internal/validation"
+)
+
+var ctx = context.Background()
+
+func newServer(t *testing.T) (*httptest.Server, *db.MemoryDatabase) {
+	t.Helper()
+
+	memDB := db.NewMemoryDatabase()
+	_, err := seed.Load(ctx, memDB, "", false)
+	require.NoError(t, err)
+
+	handler := handlers.NewHandler(memDB)
+	router := mux.NewRouter()
+	router.Use(idempotency.Middleware(idempotency.NewStore(time.Hour), handler.Username))
+	handler.RegisterRoutes(router)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, memDB
+}
+
+func newClient(server *httptest.Server, username string, opts ...client.Option) *client.Client {
+	opts = append([]client.Option{client.WithRetries(3, time.Millisecond)}, opts...)
+	return client.New(server.URL, username, "password", opts...)
+}
+
+func TestClient_Scenario(t *testing.T) {
+	server, _ := newServer(t)
+	alice := newClient(server, "alice")
+	bob := newClient(server, "bob")
+
+	_, err := bob.Login(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, alice.SendCoin(ctx, "bob", 100))
+	require.NoError(t, alice.Buy(ctx, "cup"))
+
+	catalog, err := alice.Catalog(ctx)
+	require.NoError(t, err)
+	assert.Len(t, catalog.Items, 10)
+	assert.Contains(t, catalog.Items, client.CatalogItem{Name: "cup", Price: 20})
+
+	info, err := alice.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.Coins(880), info.Coins)
+	assert.Equal(t, []client.InventoryInfo{{Type: "cup", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, []client.TransactionInfo{{ID: 1, Username: "bob", Amount: 100}}, info.CoinHistory.Sent)
+
+	info, err = bob.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.Coins(1100), info.Coins)
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	server, memDB := newServer(t)
+	alice := newClient(server, "alice")
+
+	assert.ErrorIs(t, alice.SendCoin(ctx, "ghost", 1), client.ErrNotFound)
+	assert.ErrorIs(t, alice.Buy(ctx, "yacht"), client.ErrNotFound)
+	assert.NoError(t, alice.Buy(ctx, "pink-hoody"))
+	assert.ErrorIs(t, alice.Buy(ctx, "pink-hoody"), client.ErrLimitExceeded)
+	assert.NoError(t, alice.BuyVariant(ctx, "hoody", client.VariantOptions{Size: "M", Color: "grey"}))
+	assert.ErrorIs(t, alice.BuyVariant(ctx, "hoody", client.VariantOptions{Size: "L", Color: "black"}), client.ErrInsufficientFunds)
+	assert.ErrorIs(t, alice.BuyVariant(ctx, "hoody", client.VariantOptions{Size: "XXL"}), client.ErrNotFound)
+
+	info, err := alice.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []client.InventoryInfo{
+		{Type: "hoody", Size: "M", Color: "grey", Quantity: 1},
+		{Type: "pink-hoody", Quantity: 1},
+	}, info.Inventory)
+
+	bob := newClient(server, "bob")
+	stock := 0
+	require.NoError(t, memDB.UpsertMerch(ctx, []models.Merch{{Name: "sold-out", Price: 1, Stock: &stock}}))
+	assert.ErrorIs(t, bob.Buy(ctx, "sold-out"), client.ErrOutOfStock)
+
+	err = alice.SendCoin(ctx, "alice", 1)
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "toUser", apiErr.Errors[0].Field)
+
+	_, err = newClient(server, "alice").Login(ctx)
+	assert.NoError(t, err)
+	_, err = client.New(server.URL, "alice", "wrong").Login(ctx)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestClient_Gift(t *testing.T) {
+	server, _ := newServer(t)
+	alice := newClient(server, "alice")
+	bob := newClient(server, "bob")
+	_, err := bob.Login(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, alice.Gift(ctx, "bob", "hoody", client.VariantOptions{Size: "M", Color: "black"}))
+	assert.ErrorIs(t, alice.Gift(ctx, "alice", "cup", client.VariantOptions{}), client.ErrInvalidRequest)
+	assert.ErrorIs(t, alice.Gift(ctx, "nobody", "cup", client.VariantOptions{}), client.ErrNotFound)
+
+	info, err := bob.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []client.InventoryInfo{{Type: "hoody", Size: "M", Color: "black", Quantity: 1}}, info.Inventory)
+	require.Len(t, info.CoinHistory.GiftsReceived, 1)
+	assert.Equal(t, "alice", info.CoinHistory.GiftsReceived[0].Username)
+}
+
+func TestClient_Orders(t *testing.T) {
+	server, memDB := newServer(t)
+	alice := newClient(server, "alice")
+	manager := newClient(server, "manager")
+	_, err := manager.Login(ctx)
+	require.NoError(t, err)
+	_, err = memDB.SetUserRole(ctx, "manager", models.RoleManager)
+	require.NoError(t, err)
+
+	require.NoError(t, alice.Buy(ctx, "cup"))
+	_, err = alice.Orders(ctx, "")
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	orders, err := manager.Orders(ctx, client.OrderPlaced)
+	require.NoError(t, err)
+	require.Len(t, orders.Orders, 1)
+	assert.Equal(t, "alice", orders.Orders[0].Username)
+
+	order, err := manager.SetOrderStatus(ctx, orders.Orders[0].ID, client.OrderPacked)
+	require.NoError(t, err)
+	assert.Equal(t, client.OrderPacked, order.Status)
+	_, err = manager.SetOrderStatus(ctx, order.ID, client.OrderPlaced)
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	info, err := alice.Info(ctx)
+	require.NoError(t, err)
+	require.Len(t, info.Orders, 1)
+	assert.Equal(t, client.OrderPacked, info.Orders[0].Status)
+
+	order, err = alice.CancelOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, client.OrderCancelled, order.Status)
+	_, err = alice.CancelOrder(ctx, order.ID)
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	info, err = alice.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.Coins(1000), info.Coins)
+	require.Len(t, info.CoinHistory.Refunds, 1)
+	assert.Equal(t, "cup", info.CoinHistory.Refunds[0].Item)
+}
+
+func TestClient_RefreshesRejectedToken(t *testing.T) {
+	server, _ := newServer(t)
+	alice := newClient(server, "alice", client.WithToken("expired"))
+
+	info, err := alice.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.Coins(1000), info.Coins)
+	assert.NotEqual(t, "expired", alice.Token())
+}
+
+// losingTransport delivers requests to server, but for the first lost requests it reports broken connection instead of response...
+type losingTransport struct {
+	lost     atomic.Int32
+	requests atomic.Int32
+	keys     chan string
+}
+
+func (tr *losingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || req.URL.Path == "/api/auth" {
+		return resp, err
+	}
+
+	tr.requests.Add(1)
+	tr.keys <- req.Header.Get(idempotency.Header)
+	if tr.lost.Add(-1) >= 0 {
+		_ = resp.Body.Close()
+		return nil, errors.New("connection reset by peer")
+	}
+	return resp, nil
+}
+
+func TestClient_RetriesWithIdempotencyKey(t *testing.T) {
+	server, _ := newServer(t)
+	transport := &losingTransport{keys: make(chan string, 10)}
+	transport.lost.Store(2)
+	alice := newClient(server, "alice", client.WithHTTPClient(&http.Client{Transport: transport}))
+	bob := newClient(server, "bob")
+	_, err := bob.Login(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, alice.SendCoin(ctx, "bob", 100))
+	assert.Equal(t, int32(3), transport.requests.Load())
+
+	close(transport.keys)
+	keys := map[string]bool{}
+	for key := range transport.keys {
+		keys[key] = true
+	}
+	assert.Len(t, keys, 1)
+	assert.NotContains(t, keys, "")
+
+	info, err := bob.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.Coins(1100), info.Coins, "coins must be sent once")
+}
+
+func TestClient_TokenWithoutPassword(t *testing.T) {
+	server, _ := newServer(t)
+	token, err := newClient(server, "alice").Login(ctx)
+	require.NoError(t, err)
+
+	info, err := client.New(server.URL, "alice", "", client.WithToken(token)).Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, client.Coins(1000), info.Coins)
+
+	_, err = client.New(server.URL, "alice", "", client.WithToken("expired")).Info(ctx)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := client.New(server.URL, "alice", "password", client.WithRetries(4, time.Millisecond)).Login(ctx)
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Equal(t, int32(4), requests.Load())
+}
+
+func TestClient_ErrorCodes(t *testing.T) {
+	var code client.ErrorCode
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(models.ErrorResponse{Code: code, Errors: []validation.FieldError{{Message: "Insufficient coins"}}})
+	}))
+	defer server.Close()
+
+	login := func(errorCode client.ErrorCode) error {
+		code = errorCode
+		_, err := client.New(server.URL, "alice", "password").Login(ctx)
+		return err
+	}
+	assert.ErrorIs(t, login(client.CodeLimitExceeded), client.ErrLimitExceeded)
+	assert.ErrorIs(t, login(client.CodeOutOfStock), client.ErrOutOfStock)
+	err := login("")
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+	assert.NotErrorIs(t, err, client.ErrInsufficientFunds)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"merch_store/internal/models"
+	"merch_store/internal/validation"
+)
+
+// Errors APIError unwraps to, callers should check them with errors.Is.
+var (
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrInsufficientFunds = errors.New("insufficient coins")
+	ErrOutOfStock        = errors.New("out of stock")
+	ErrLimitExceeded     = errors.New("purchase limit exceeded")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrForbidden         = errors.New("forbidden")
+	ErrNotFound          = errors.New("not found")
+	ErrConflict          = errors.New("conflict")
+	ErrServer            = errors.New("server error")
+)
+
+// APIError is error response returned by server, Code is set for errors caused by business rules...
+type APIError struct {
+	StatusCode int
+	Code       ErrorCode
+	Errors     []validation.FieldError
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: status}
+
+	var response models.ErrorResponse
+	if err := json.Unmarshal(body, &response); err == nil {
+		apiErr.Code, apiErr.Errors = response.Code, response.Errors
+	}
+	if len(apiErr.Errors) == 0 {
+		apiErr.Errors = []validation.FieldError{{Message: http.StatusText(status)}}
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		if fieldErr.Field != "" {
+			messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+		} else {
+			messages = append(messages, fieldErr.Message)
+		}
+	}
+	return fmt.Sprintf("merch store responded %d: %s", e.StatusCode, strings.Join(messages, "; "))
+}
+
+// Unwrap returns sentinel error matching error code or status code...
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.Code == CodeInsufficientFunds:
+		return ErrInsufficientFunds
+	case e.Code == CodeOutOfStock:
+		return ErrOutOfStock
+	case e.Code == CodeLimitExceeded:
+		return ErrLimitExceeded
+	case e.StatusCode == http.StatusBadRequest, e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrInvalidRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import "merch_store/internal/models"
+
+// Types of requests and responses are the ones server uses, so client and server can't drift apart.
+type (
+	Coins           = models.Coins
+	InfoResponse    = models.InfoResponse
+	InventoryInfo   = models.InventoryInfo
+	CoinHistory     = models.CoinHistory
+	TransactionInfo = models.TransactionInfo
+	RefundInfo      = models.RefundInfo
+	GiftInfo        = models.GiftInfo
+	CatalogResponse = models.CatalogResponse
+	CatalogItem     = models.CatalogItem
+	PurchaseLimit   = models.PurchaseLimit
+	CatalogVariant  = models.CatalogVariant
+	VariantOptions  = models.VariantOptions
+	Order           = models.Order
+	OrderStatus     = models.OrderStatus
+	OrdersResponse  = models.OrdersResponse
+	ErrorCode       = models.ErrorCode
+)
+
+// Order statuses.
+const (
+	OrderPlaced     = models.OrderPlaced
+	OrderPacked     = models.OrderPacked
+	OrderHandedOver = models.OrderHandedOver
+	OrderCancelled  = models.OrderCancelled
+)
+
+// Error codes, APIError.Code holds one of them for errors caused by business rules.
+const (
+	CodeInsufficientFunds = models.CodeInsufficientFunds
+	CodeOutOfStock        = models.CodeOutOfStock
+	CodeLimitExceeded     = models.CodeLimitExceeded
+	CodeVariantNotFound   = models.CodeVariantNotFound
+	CodeStatusTransition  = models.CodeStatusTransition
+	CodeRefundExpired     = models.CodeRefundExpired
+	CodeCoinsOverflow     = models.CodeCoinsOverflow
+)
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"merch_store/internal/db"
+	"merch_store/internal/models"
+)
+
+// errNotConfirmed is returned when command would change data but --confirm was not given.
+var errNotConfirmed = errors.New("refusing to change data without --confirm")
+
+// errMismatch is returned by reconcile when balances differ from ledger, so it can be used in scripts.
+var errMismatch = errors.New("balances differ from ledger")
+
+// parse parses command flags and checks there are no positional arguments...
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: %s expects no positional arguments", errUsage, fs.Name())
+	}
+	return nil
+}
+
+// print writes value as JSON in -json mode and calls text otherwise...
+func (a *app) print(value any, text func(w *tabwriter.Writer)) error {
+	if a.json {
+		return writeJSON(a.stdout, value)
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func (a *app) users(args []string) (func(ctx context.Context) error, error) {
+	if err := parse(a.flags("users"), args); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		users, err := a.store.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		type user struct {
+			ID       int          `json:"id"`
+			Username string       `json:"username"`
+			Coins    models.Coins `json:"coins"`
+			Role     models.Role  `json:"role"`
+		}
+		result := make([]user, 0, len(users))
+		for _, u := range users {
+			result = append(result, user{ID: u.ID, Username: u.Username, Coins: u.Coins, Role: u.Role})
+		}
+
+		return a.print(result, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "ID\tUSER\tCOINS\tROLE")
+			for _, u := range result {
+				fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", u.ID, u.Username, u.Coins, u.Role)
+			}
+		})
+	}, nil
+}
+
+func (a *app) role(args []string) (func(ctx context.Context) error, error) {
+	var username, role string
+	var confirm bool
+	fs := a.flags("role")
+	fs.StringVar(&username, "user", "", "user name")
+	fs.StringVar(&role, "set", "", "employee or manager")
+	fs.BoolVar(&confirm, "confirm", false, "change role")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if username == "" || !models.Role(role).Valid() {
+		return nil, fmt.Errorf("%w: role expects -user and -set employee or manager", errUsage)
+	}
+	if !confirm {
+		return nil, errNotConfirmed
+	}
+
+	return func(ctx context.Context) error {
+		user, err := a.store.SetUserRole(ctx, username, models.Role(role))
+		if err != nil {
+			return err
+		}
+
+		return a.print(map[string]any{"username": user.Username, "role": user.Role}, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "%s is %s\n", user.Username, user.Role)
+		})
+	}, nil
+}
+
+func (a *app) adjust(args []string) (func(ctx context.Context) error, error) {
+	var username, reason string
+	var amount int64
+	var confirm bool
+	fs := a.flags("adjust")
+	fs.StringVar(&username, "user", "", "user name")
+	fs.Int64Var(&amount, "amount", 0, "coins to add, negative to take")
+	fs.StringVar(&reason, "reason", "", "reason recorded in ledger")
+	fs.BoolVar(&confirm, "confirm", false, "change balance")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if username == "" || amount == 0 || reason == "" {
+		return nil, fmt.Errorf("%w: adjust expects -user, non-zero -amount and -reason", errUsage)
+	}
+	if !confirm {
+		return nil, errNotConfirmed
+	}
+
+	return func(ctx context.Context) error {
+		user, err := a.store.AdjustCoins(ctx, username, models.Coins(amount), reason)
+		if err != nil {
+			return err
+		}
+
+		return a.print(map[string]any{"username": user.Username, "amount": amount, "coins": user.Coins}, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "Adjusted %s by %d coins, balance is %d\n", user.Username, amount, user.Coins)
+		})
+	}, nil
+}
+
+func (a *app) reverse(args []string) (func(ctx context.Context) error, error) {
+	var transactionID int
+	var confirm bool
+	fs := a.flags("reverse")
+	fs.IntVar(&transactionID, "transfer", 0, "transfer id, users see it in their history")
+	fs.BoolVar(&confirm, "confirm", false, "reverse transfer")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if transactionID <= 0 {
+		return nil, fmt.Errorf("%w: reverse expects positive -transfer", errUsage)
+	}
+	if !confirm {
+		return nil, errNotConfirmed
+	}
+
+	return func(ctx context.Context) error {
+		reversal, err := a.store.ReverseTransfer(ctx, transactionID)
+		if err != nil {
+			return err
+		}
+
+		return a.print(reversal, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "Reversed transfer %d of %d coins with transfer %d\n", transactionID, reversal.Amount, reversal.ID)
+		})
+	}, nil
+}
+
+func (a *app) addMerch(args []string) (func(ctx context.Context) error, error) {
+	var name string
+	var price int64
+	var stock, lifetime, perWindow, windowDays int
+	var confirm bool
+	fs := a.flags("add-merch")
+	fs.StringVar(&name, "name", "", "merch name")
+	fs.Int64Var(&price, "price", 0, "price in coins")
+	fs.IntVar(&stock, "stock", -1, "number of items for limited edition, unlimited by default")
+	fs.IntVar(&lifetime, "lifetime-limit", 0, "items one user can buy in total")
+	fs.IntVar(&perWindow, "window-limit", 0, "items one user can buy within -window-days")
+	fs.IntVar(&windowDays, "window-days", 0, "size of rolling window for -window-limit")
+	fs.BoolVar(&confirm, "confirm", false, "change price or limit of existing merch")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if name == "" || price <= 0 || stock < -1 {
+		return nil, fmt.Errorf("%w: add-merch expects -name, positive -price and non-negative -stock", errUsage)
+	}
+	item := models.Merch{Name: name, Price: models.Coins(price), Limit: models.NewPurchaseLimit(lifetime, perWindow, windowDays)}
+	if err := item.Limit.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if stock >= 0 {
+		item.Stock = &stock
+	}
+
+	return func(ctx context.Context) error {
+		existing, err := a.store.GetMerchByName(ctx, name)
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+		case err != nil:
+			return err
+		case item.Stock != nil:
+			// UpsertMerch keeps stock of existing merch
+			return fmt.Errorf("%w: %s already exists, use stock command to change its stock", errUsage, name)
+		case existing.Price == item.Price && sameLimit(existing.Limit, item.Limit):
+			return a.print(existing, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "%s already costs %d coins, %s\n", name, price, existing.Limit)
+			})
+		case !confirm:
+			return fmt.Errorf("%s costs %d coins, %s: %w", name, existing.Price, existing.Limit, errNotConfirmed)
+		}
+
+		if err = a.store.UpsertMerch(ctx, []models.Merch{item}); err != nil {
+			return err
+		}
+
+		merch, err := a.store.GetMerchByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		return a.print(merch, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "%s costs %d coins, %s, %s\n", merch.Name, merch.Price, stockText(merch.Stock), merch.Limit)
+		})
+	}, nil
+}
+
+func (a *app) stock(args []string) (func(ctx context.Context) error, error) {
+	var name string
+	var options models.VariantOptions
+	var set int
+	var unlimited, confirm bool
+	fs := a.flags("stock")
+	fs.StringVar(&name, "name", "", "merch name")
+	fs.StringVar(&options.Size, "size", "", "size of variant")
+	fs.StringVar(&options.Color, "color", "", "color of variant")
+	fs.IntVar(&set, "set", -1, "number of items left")
+	fs.BoolVar(&unlimited, "unlimited", false, "make supply unlimited")
+	fs.BoolVar(&confirm, "confirm", false, "change stock")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if name == "" || (set < 0) == !unlimited {
+		return nil, fmt.Errorf("%w: stock expects -name and either non-negative -set or -unlimited", errUsage)
+	}
+	if !confirm {
+		return nil, errNotConfirmed
+	}
+
+	var stock *int
+	if !unlimited {
+		stock = &set
+	}
+
+	return func(ctx context.Context) error {
+		merch, err := a.store.SetMerchStock(ctx, name, options, stock)
+		if err != nil {
+			return err
+		}
+		return a.print(merch, func(w *tabwriter.Writer) {
+			if options == (models.VariantOptions{}) {
+				fmt.Fprintf(w, "%s: %s\n", merch.Name, stockText(merch.Stock))
+				return
+			}
+			variant, _ := merch.Variant(options)
+			fmt.Fprintf(w, "%s (%s): %s\n", merch.Name, options, stockText(variant.Stock))
+		})
+	}, nil
+}
+
+func (a *app) refund(args []string) (func(ctx context.Context) error, error) {
+	var orderID int
+	var reason string
+	var confirm bool
+	fs := a.flags("refund")
+	fs.IntVar(&orderID, "order", 0, "order id")
+	fs.StringVar(&reason, "reason", "", "reason recorded in ledger")
+	fs.BoolVar(&confirm, "confirm", false, "refund order")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if orderID <= 0 || reason == "" {
+		return nil, fmt.Errorf("%w: refund expects positive -order and -reason", errUsage)
+	}
+	if !confirm {
+		return nil, errNotConfirmed
+	}
+
+	return func(ctx context.Context) error {
+		// handed over item is expected to be returned to office before refund
+		order, err := a.store.RefundOrder(ctx, orderID, models.RefundRules{HandedOver: true}, reason)
+		if err != nil {
+			return err
+		}
+
+		return a.print(order, func(w *tabwriter.Writer) {
+			item := order.Item
+			if options := (models.VariantOptions{Size: order.Size, Color: order.Color}); options != (models.VariantOptions{}) {
+				item = fmt.Sprintf("%s (%s)", order.Item, options)
+			}
+			fmt.Fprintf(w, "Refunded order %d of %s for %s\n", order.ID, order.Username, item)
+		})
+	}, nil
+}
+
+func sameLimit(a, b *models.PurchaseLimit) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func stockText(stock *int) string {
+	if stock == nil {
+		return "unlimited stock"
+	}
+	return fmt.Sprintf("%d in stock", *stock)
+}
+
+func (a *app) ledger(args []string) (func(ctx context.Context) error, error) {
+	var username, format, output string
+	fs := a.flags("ledger")
+	fs.StringVar(&username, "user", "", "user name, all users by default")
+	fs.StringVar(&format, "format", "csv", "csv or json")
+	fs.StringVar(&output, "o", "", "output file, stdout by default")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if format != "csv" && format != "json" {
+		return nil, fmt.Errorf("%w: unknown format %q", errUsage, format)
+	}
+
+	return func(ctx context.Context) (err error) {
+		ledger, err := a.store.Ledger(ctx, username)
+		if err != nil {
+			return err
+		}
+		if ledger == nil {
+			ledger = []models.LedgerEntry{}
+		}
+
+		w := a.stdout
+		if output != "" {
+			file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = errors.Join(err, file.Close())
+			}()
+			w = file
+		}
+
+		if format == "json" {
+			return writeJSON(w, ledger)
+		}
+		return writeLedgerCSV(w, ledger)
+	}, nil
+}
+
+func writeLedgerCSV(w io.Writer, ledger []models.LedgerEntry) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"time", "username", "kind", "subject", "amount", "reason"})
+	for _, entry := range ledger {
+		_ = writer.Write([]string{
+			entry.Time.UTC().Format(time.RFC3339Nano),
+			entry.Username,
+			entry.Kind,
+			entry.Subject,
+			strconv.FormatInt(int64(entry.Amount), 10),
+			entry.Reason,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (a *app) reconcile(args []string) (func(ctx context.Context) error, error) {
+	var fix, confirm bool
+	fs := a.flags("reconcile")
+	fs.BoolVar(&fix, "fix", false, "record differences as adjustments, so ledger matches balances")
+	fs.BoolVar(&confirm, "confirm", false, "confirm -fix")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if fix && !confirm {
+		return nil, errNotConfirmed
+	}
+
+	return func(ctx context.Context) error {
+		reconcile := a.store.Reconcile
+		if fix {
+			reconcile = a.store.RecordReconciliation
+		}
+		mismatches, err := reconcile(ctx)
+		if err != nil {
+			return err
+		}
+		if mismatches == nil {
+			mismatches = []models.BalanceMismatch{}
+		}
+
+		err = a.print(mismatches, func(w *tabwriter.Writer) {
+			if len(mismatches) == 0 {
+				fmt.Fprintln(w, "All balances match ledger")
+				return
+			}
+			fmt.Fprintln(w, "USER\tCOINS\tEXPECTED\tDIFFERENCE")
+			for _, mismatch := range mismatches {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", mismatch.Username, mismatch.Coins, mismatch.Expected,
+					mismatch.Coins-mismatch.Expected)
+			}
+			if fix {
+				fmt.Fprintf(w, "Recorded %d adjustments\n", len(mismatches))
+			}
+		})
+		if err != nil || fix || len(mismatches) == 0 {
+			return err
+		}
+		return fmt.Errorf("%d %w", len(mismatches), errMismatch)
+	}, nil
+}
@@ -0,0 +1,140 @@
+// Command merchadmin runs administrative operations directly against merch store database.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"merch_store/internal/db"
+	"merch_store/internal/models"
+)
+
+const usage = `Usage:
+  merchadmin [-json] <command> [arguments]
+
+Database connection is configured with DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME.
+
+Commands:
+  users                                                   list users and balances
+  role -user name -set employee|manager --confirm         change user role, store managers handle orders
+  adjust -user name -amount n -reason text --confirm      add coins to user balance, negative amount takes them
+  reverse -transfer id --confirm                          send coins of mistaken transfer back to sender
+  add-merch -name item -price n [-stock n] [-lifetime-limit n] [-window-limit n -window-days n] [--confirm]
+                                                          add merch, --confirm is required to change existing price or limit
+  stock -name item [-size s] [-color c] (-set n | -unlimited) --confirm
+                                                          change number of items left of merch or its variant
+  refund -order id -reason text --confirm                 cancel order even if handed over, return coins and put item back to stock
+  ledger [-user name] [-format csv|json] [-o file]        export balance changes of one or all users
+  reconcile [-fix --confirm]                              compare balances with ledger, -fix records differences as adjustments`
+
+// errUsage is returned for wrong arguments, usage is printed for it.
+var errUsage = errors.New("wrong arguments")
+
+// store is part of db.Database used by commands.
+type store interface {
+	ListUsers(ctx context.Context) ([]models.User, error)
+	SetUserRole(ctx context.Context, username string, role models.Role) (*models.User, error)
+	AdjustCoins(ctx context.Context, username string, amount models.Coins, reason string) (*models.User, error)
+	ReverseTransfer(ctx context.Context, transactionID int) (*models.Transaction, error)
+	GetMerchByName(ctx context.Context, name string) (*models.Merch, error)
+	UpsertMerch(ctx context.Context, items []models.Merch) error
+	SetMerchStock(ctx context.Context, name string, options models.VariantOptions, stock *int) (*models.Merch, error)
+	RefundOrder(ctx context.Context, orderID int, rules models.RefundRules, reason string) (*models.Order, error)
+	Ledger(ctx context.Context, username string) ([]models.LedgerEntry, error)
+	Reconcile(ctx context.Context) ([]models.BalanceMismatch, error)
+	RecordReconciliation(ctx context.Context) ([]models.BalanceMismatch, error)
+}
+
+var _ store = (*db.Database)(nil)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, func(ctx context.Context) (store, func(), error) {
+		database, err := db.ConnectFromEnv(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return database, func() { _ = database.Close() }, nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "merchadmin: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// app holds options shared by all commands...
+type app struct {
+	json   bool
+	stdout io.Writer
+	store  store
+}
+
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&a.json, "json", a.json, "print JSON")
+	return fs
+}
+
+// run parses arguments and runs command, connect is called only after arguments are known to be valid...
+func run(ctx context.Context, args []string, stdout io.Writer, connect func(ctx context.Context) (store, func(), error)) error {
+	a := &app{stdout: stdout}
+
+	global := a.flags("merchadmin")
+	if err := global.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if global.NArg() == 0 {
+		return errUsage
+	}
+
+	var command func(ctx context.Context) error
+	var err error
+	name, args := global.Arg(0), global.Args()[1:]
+	switch name {
+	case "users":
+		command, err = a.users(args)
+	case "role":
+		command, err = a.role(args)
+	case "adjust":
+		command, err = a.adjust(args)
+	case "reverse":
+		command, err = a.reverse(args)
+	case "add-merch":
+		command, err = a.addMerch(args)
+	case "stock":
+		command, err = a.stock(args)
+	case "refund":
+		command, err = a.refund(args)
+	case "ledger":
+		command, err = a.ledger(args)
+	case "reconcile":
+		command, err = a.reconcile(args)
+	default:
+		err = fmt.Errorf("%w: unknown command %q", errUsage, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	s, closeStore, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	a.store = s
+	return command(ctx)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"merch_store/internal/db"
+	"merch_store/internal/db/dbtest"
+	"merch_store/internal/db/pgtest"
+	"merch_store/internal/models"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Run(m))
+}
+
+// merchadmin runs command against database and returns its output...
+func merchadmin(t *testing.T, database store, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	err := run(context.Background(), args, &stdout, func(context.Context) (store, func(), error) {
+		if database == nil {
+			t.Fatal("database must not be opened")
+		}
+		return database, func() {}, nil
+	})
+	return stdout.String(), err
+}
+
+func TestMerchadmin_RefusesWithoutConfirm(t *testing.T) {
+	cases := [][]string{
+		{"adjust", "-user", "alice", "-amount", "10", "-reason", "bonus"},
+		{"reconcile", "-fix"},
+		{"stock", "-name", "pink-hoody", "-set", "10"},
+		{"role", "-user", "alice", "-set", "manager"},
+		{"refund", "-order", "1", "-reason", "wrong size"},
+		{"reverse", "-transfer", "1"},
+	}
+	for _, args := range cases {
+		_, err := merchadmin(t, nil, args...)
+		assert.ErrorIs(t, err, errNotConfirmed, "%v", args)
+	}
+}
+
+func TestMerchadmin_WrongArguments(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"drop-tables"},
+		{"role", "-user", "alice", "-set", "admin", "--confirm"},
+		{"role", "-set", "manager", "--confirm"},
+		{"users", "extra"},
+		{"adjust", "-user", "alice", "-amount", "0", "-reason", "bonus", "--confirm"},
+		{"adjust", "-user", "alice", "-amount", "10", "--confirm"},
+		{"add-merch", "-name", "cup", "-price", "0"},
+		{"ledger", "-format", "xml"},
+		{"add-merch", "-name", "cup", "-price", "10", "-stock", "-5"},
+		{"add-merch", "-name", "cup", "-price", "10", "-window-limit", "1"},
+		{"stock", "-name", "cup", "--confirm"},
+		{"stock", "-name", "cup", "-set", "3", "-unlimited", "--confirm"},
+		{"refund", "-order", "0", "-reason", "wrong size", "--confirm"},
+		{"refund", "-order", "1", "--confirm"},
+		{"reverse", "--confirm"},
+		{"reverse", "-transfer", "-1", "--confirm"},
+	}
+	for _, args := range cases {
+		_, err := merchadmin(t, nil, args...)
+		assert.ErrorIs(t, err, errUsage, "%v", args)
+	}
+}
+
+func TestMerchadmin(t *testing.T) {
+	t.Parallel()
+	database := dbtest.NewPostgres(t)
+
+	ctx := context.Background()
+	require.NoError(t, database.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+	require.NoError(t, database.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}))
+
+	out, err := merchadmin(t, database, "adjust", "-user", "alice", "-amount", "-100", "-reason", "fine", "--confirm")
+	require.NoError(t, err)
+	assert.Equal(t, "Adjusted alice by -100 coins, balance is 900\n", out)
+
+	_, err = merchadmin(t, database, "adjust", "-user", "nobody", "-amount", "5", "-reason", "bonus", "--confirm")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	out, err = merchadmin(t, database, "-json", "users")
+	require.NoError(t, err)
+	var users []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &users))
+	assert.Equal(t, []map[string]any{
+		{"id": users[0]["id"], "username": "alice", "coins": float64(900), "role": "employee"},
+		{"id": users[1]["id"], "username": "bob", "coins": float64(1000), "role": "employee"},
+	}, users)
+
+	out, err = merchadmin(t, database, "role", "-user", "bob", "-set", "manager", "--confirm")
+	require.NoError(t, err)
+	assert.Equal(t, "bob is manager\n", out)
+	_, err = merchadmin(t, database, "role", "-user", "nobody", "-set", "manager", "--confirm")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	out, err = merchadmin(t, database, "add-merch", "-name", "mug", "-price", "30")
+	require.NoError(t, err)
+	assert.Equal(t, "mug costs 30 coins, unlimited stock, no limit\n", out)
+	_, err = merchadmin(t, database, "add-merch", "-name", "mug", "-price", "40")
+	assert.ErrorIs(t, err, errNotConfirmed)
+	_, err = merchadmin(t, database, "add-merch", "-name", "mug", "-price", "40", "--confirm")
+	require.NoError(t, err)
+
+	out, err = merchadmin(t, database, "add-merch", "-name", "pink-mug", "-price", "60", "-stock", "2")
+	require.NoError(t, err)
+	assert.Equal(t, "pink-mug costs 60 coins, 2 in stock, no limit\n", out)
+	_, err = merchadmin(t, database, "add-merch", "-name", "pink-mug", "-price", "60", "-window-limit", "1", "-window-days", "91")
+	assert.ErrorIs(t, err, errNotConfirmed)
+	out, err = merchadmin(t, database, "add-merch", "-name", "pink-mug", "-price", "60", "-window-limit", "1", "-window-days", "91", "--confirm")
+	require.NoError(t, err)
+	assert.Equal(t, "pink-mug costs 60 coins, 2 in stock, 1 per 91 days\n", out)
+	_, err = merchadmin(t, database, "add-merch", "-name", "pink-mug", "-price", "60", "-stock", "5", "--confirm")
+	assert.ErrorIs(t, err, errUsage)
+	out, err = merchadmin(t, database, "stock", "-name", "pink-mug", "-set", "5", "--confirm")
+	require.NoError(t, err)
+	assert.Equal(t, "pink-mug: 5 in stock\n", out)
+	out, err = merchadmin(t, database, "stock", "-name", "pink-mug", "-unlimited", "--confirm")
+	require.NoError(t, err)
+	assert.Equal(t, "pink-mug: unlimited stock\n", out)
+
+	require.NoError(t, database.UpsertMerch(ctx, []models.Merch{{Name: "cap", Price: 15, Variants: []models.MerchVariant{
+		{VariantOptions: models.VariantOptions{Size: "S"}},
+		{VariantOptions: models.VariantOptions{Size: "M"}},
+	}}}))
+	out, err = merchadmin(t, database, "stock", "-name", "cap", "-size", "M", "-set", "3", "--confirm")
+	require.NoError(t, err)
+	assert.Equal(t, "cap (M): 3 in stock\n", out)
+	_, err = merchadmin(t, database, "stock", "-name", "cap", "-size", "XL", "-set", "3", "--confirm")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	mug, err := database.GetMerchByName(ctx, "mug")
+	require.NoError(t, err)
+	alice, err := database.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	require.NoError(t, database.BuyMerch(ctx, alice.ID, mug.ID, 0, mug.Price))
+	require.NoError(t, database.BuyMerch(ctx, alice.ID, mug.ID, 0, mug.Price))
+	orders, err := database.ListOrders(ctx, alice.ID, "")
+	require.NoError(t, err)
+	_, err = database.SetOrderStatus(ctx, orders[1].ID, models.OrderPacked)
+	require.NoError(t, err)
+	_, err = database.SetOrderStatus(ctx, orders[1].ID, models.OrderHandedOver)
+	require.NoError(t, err)
+
+	out, err = merchadmin(t, database, "refund", "-order", strconv.Itoa(orders[1].ID), "-reason", "broken handle", "--confirm")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("Refunded order %d of alice for mug\n", orders[1].ID), out)
+	_, err = merchadmin(t, database, "refund", "-order", strconv.Itoa(orders[1].ID), "-reason", "again", "--confirm")
+	assert.ErrorIs(t, err, models.ErrStatusTransition)
+
+	path := filepath.Join(t.TempDir(), "ledger.csv")
+	_, err = merchadmin(t, database, "ledger", "-user", "alice", "-o", path)
+	require.NoError(t, err)
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, []string{"time", "username", "kind", "subject", "amount", "reason"}, records[0])
+	assert.Equal(t, []string{"alice", "adjustment", "", "-100", "fine"}, records[1][1:])
+	assert.Equal(t, []string{"alice", "purchase", "mug", "-40", ""}, records[2][1:])
+	assert.Equal(t, []string{"alice", "purchase", "mug", "-40", ""}, records[3][1:])
+	assert.Equal(t, []string{"alice", "refund", "mug", "40", "broken handle"}, records[4][1:])
+
+	out, err = merchadmin(t, database, "reconcile")
+	require.NoError(t, err)
+	assert.Equal(t, "All balances match ledger\n", out)
+
+	_, err = database.Pool.Exec(ctx, "UPDATE users SET coins = coins - 5 WHERE username = 'bob'")
+	require.NoError(t, err)
+	out, err = merchadmin(t, database, "reconcile")
+	assert.ErrorIs(t, err, errMismatch)
+	assert.Contains(t, out, "bob")
+
+	_, err = merchadmin(t, database, "reconcile", "-fix", "--confirm")
+	require.NoError(t, err)
+	_, err = merchadmin(t, database, "reconcile")
+	assert.NoError(t, err)
+
+	bob, err := database.GetUserByUsername(ctx, "bob")
+	require.NoError(t, err)
+	require.NoError(t, database.TransferCoins(ctx, alice.ID, bob.ID, 10))
+	history, err := database.GetUserTransactions(ctx, alice.ID)
+	require.NoError(t, err)
+	transfer := strconv.Itoa(history.Sent[0].ID)
+	out, err = merchadmin(t, database, "reverse", "-transfer", transfer, "--confirm")
+	require.NoError(t, err)
+	assert.Regexp(t, "^Reversed transfer "+transfer+" of 10 coins with transfer [0-9]+\n$", out)
+	_, err = merchadmin(t, database, "reverse", "-transfer", transfer, "--confirm")
+	assert.ErrorIs(t, err, db.ErrNotReversible)
+	_, err = merchadmin(t, database, "reconcile")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"merch_store/client"
+)
+
+// passwordEnv lets scripts log in without stdin.
+const passwordEnv = "MERCHCTL_PASSWORD"
+
+const defaultServer = "http://localhost:8080"
+
+// parse parses command flags and checks number of positional arguments...
+func (a *app) parse(name string, args []string, positional int) ([]string, error) {
+	fs := a.flags(name)
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() != positional {
+		return nil, fmt.Errorf("%w: %s expects %d arguments", errUsage, name, positional)
+	}
+	return fs.Args(), nil
+}
+
+// client creates API client from saved token...
+func (a *app) client() (*client.Client, error) {
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("%w: not logged in", client.ErrUnauthorized)
+	}
+	return client.New(cfg.Server, cfg.Username, "", client.WithToken(cfg.Token)), nil
+}
+
+// print writes value as JSON in -json mode and calls text otherwise...
+func (a *app) print(value any, text func(w *tabwriter.Writer)) error {
+	if a.json {
+		encoder := json.NewEncoder(a.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+func (a *app) login(ctx context.Context, args []string) error {
+	var server, username string
+	fs := a.flags("login")
+	fs.StringVar(&server, "server", "", "server URL, saved one or "+defaultServer+" by default")
+	fs.StringVar(&username, "username", "", "user name, saved one by default")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return err
+	}
+	if server != "" {
+		cfg.Server = server
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	if username != "" {
+		cfg.Username = username
+	}
+	if cfg.Username == "" || fs.NArg() != 0 {
+		return fmt.Errorf("%w: login expects -username", errUsage)
+	}
+
+	password, err := a.password()
+	if err != nil {
+		return err
+	}
+
+	cfg.Token, err = client.New(cfg.Server, cfg.Username, password).Login(ctx)
+	if err != nil {
+		return err
+	}
+	if err = saveConfig(a.configPath, cfg); err != nil {
+		return err
+	}
+
+	return a.print(map[string]string{"server": cfg.Server, "username": cfg.Username}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Logged in to %s as %s\n", cfg.Server, cfg.Username)
+	})
+}
+
+// password reads password from environment or first line of stdin...
+func (a *app) password() (string, error) {
+	if password := os.Getenv(passwordEnv); password != "" {
+		return password, nil
+	}
+
+	if !a.json {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(a.stdin).ReadString('\n')
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		if err == nil {
+			err = errors.New("password is empty")
+		}
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return password, nil
+}
+
+func (a *app) balance(ctx context.Context, args []string) error {
+	if _, err := a.parse("balance", args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(map[string]client.Coins{"coins": info.Coins}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "%d coins\n", info.Coins)
+	})
+}
+
+func (a *app) send(ctx context.Context, args []string) error {
+	args, err := a.parse("send", args, 2)
+	if err != nil {
+		return err
+	}
+	toUser := args[0]
+	amount, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive integer, got %q", errUsage, args[1])
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if err = c.SendCoin(ctx, toUser, client.Coins(amount)); err != nil {
+		return err
+	}
+
+	return a.print(map[string]any{"toUser": toUser, "amount": amount}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Sent %d coins to %s\n", amount, toUser)
+	})
+}
+
+func (a *app) buy(ctx context.Context, args []string) error {
+	var options client.VariantOptions
+	var to string
+	fs := a.flags("buy")
+	fs.StringVar(&options.Size, "size", "", "size of variant")
+	fs.StringVar(&options.Color, "color", "", "color of variant")
+	fs.StringVar(&to, "to", "", "user to gift item to")
+	// flags may go both before and after item
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("%w: buy expects item", errUsage)
+	}
+	item := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: buy expects 1 argument", errUsage)
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if to != "" {
+		err = c.Gift(ctx, to, item, options)
+	} else {
+		err = c.BuyVariant(ctx, item, options)
+	}
+	if err != nil {
+		return err
+	}
+
+	name := item
+	if options != (client.VariantOptions{}) {
+		name = fmt.Sprintf("%s (%s)", item, options)
+	}
+	if to != "" {
+		name += " for " + to
+	}
+	return a.print(map[string]any{"item": item, "size": options.Size, "color": options.Color, "to": to}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Bought %s\n", name)
+	})
+}
+
+func (a *app) history(ctx context.Context, args []string) error {
+	if _, err := a.parse("history", args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(info.CoinHistory, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tDIRECTION\tUSER\tAMOUNT\tNOTE")
+		for _, transaction := range info.CoinHistory.Received {
+			fmt.Fprintf(w, "%d\treceived from\t%s\t%d\t%s\n", transaction.ID, transaction.Username, transaction.Amount,
+				reversalNote(transaction))
+		}
+		for _, transaction := range info.CoinHistory.Sent {
+			fmt.Fprintf(w, "%d\tsent to\t%s\t%d\t%s\n", transaction.ID, transaction.Username, transaction.Amount,
+				reversalNote(transaction))
+		}
+		for _, refund := range info.CoinHistory.Refunds {
+			item := orderItem(client.Order{Item: refund.Item, Size: refund.Size, Color: refund.Color})
+			fmt.Fprintf(w, "\trefunded for\t%s\t%d\torder %d, %s\n", item, refund.Amount, refund.OrderID, refund.Reason)
+		}
+		for _, gift := range info.CoinHistory.GiftsSent {
+			item := orderItem(client.Order{Item: gift.Item, Size: gift.Size, Color: gift.Color})
+			fmt.Fprintf(w, "\tgift to\t%s\t%d\torder %d, %s%s\n", gift.Username, gift.Amount, gift.OrderID, item,
+				refundNote(gift))
+		}
+		for _, gift := range info.CoinHistory.GiftsReceived {
+			item := orderItem(client.Order{Item: gift.Item, Size: gift.Size, Color: gift.Color})
+			fmt.Fprintf(w, "\tgift from\t%s\t\torder %d, %s%s\n", gift.Username, gift.OrderID, item, refundNote(gift))
+		}
+	})
+}
+
+func reversalNote(transaction client.TransactionInfo) string {
+	switch {
+	case transaction.ReversalOf != 0:
+		return fmt.Sprintf("reversal of %d", transaction.ReversalOf)
+	case transaction.ReversedBy != 0:
+		return fmt.Sprintf("reversed by %d", transaction.ReversedBy)
+	default:
+		return ""
+	}
+}
+
+func refundNote(gift client.GiftInfo) string {
+	if gift.Refunded {
+		return ", refunded"
+	}
+	return ""
+}
+
+func (a *app) catalog(ctx context.Context, args []string) error {
+	if _, err := a.parse("catalog", args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	catalog, err := c.Catalog(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(catalog, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ITEM\tPRICE\tSTOCK\tLIMIT")
+		for _, item := range catalog.Items {
+			if len(item.Variants) == 0 {
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", item.Name, item.Price, stockText(item.Stock), item.Limit)
+				continue
+			}
+			// every variant is listed, so it's clear what -size and -color to pass to buy
+			for _, variant := range item.Variants {
+				stock := variant.Stock
+				if item.Stock != nil && (stock == nil || *item.Stock < *stock) {
+					stock = item.Stock
+				}
+				fmt.Fprintf(w, "%s (%s)\t%d\t%s\t%s\n", item.Name, variant.VariantOptions, variant.Price, stockText(stock), item.Limit)
+			}
+		}
+	})
+}
+
+func (a *app) orders(ctx context.Context, args []string) error {
+	var all bool
+	var status string
+	fs := a.flags("orders")
+	fs.BoolVar(&all, "all", false, "show orders of all users")
+	fs.StringVar(&status, "status", "", "show only orders in status")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: orders expects no arguments", errUsage)
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	var orders []client.Order
+	if all {
+		response, err := c.Orders(ctx, client.OrderStatus(status))
+		if err != nil {
+			return err
+		}
+		orders = response.Orders
+	} else {
+		info, err := c.Info(ctx)
+		if err != nil {
+			return err
+		}
+		for _, order := range info.Orders {
+			if status == "" || order.Status == client.OrderStatus(status) {
+				orders = append(orders, order)
+			}
+		}
+	}
+	if orders == nil {
+		orders = []client.Order{}
+	}
+
+	return a.print(orders, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tUSER\tITEM\tGIFT TO\tSTATUS\tPLACED")
+		for _, order := range orders {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", order.ID, order.Username, orderItem(order), order.Recipient,
+				order.Status, order.CreatedAt.Format(time.DateTime))
+		}
+	})
+}
+
+func (a *app) order(ctx context.Context, args []string) error {
+	args, err := a.parse("order", args, 2)
+	if err != nil {
+		return err
+	}
+	id, err := orderID(args[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	order, err := c.SetOrderStatus(ctx, id, client.OrderStatus(args[1]))
+	if err != nil {
+		return err
+	}
+
+	return a.print(order, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Order %d of %s for %s is %s\n", order.ID, order.Username, orderItem(*order), order.Status)
+	})
+}
+
+func (a *app) cancel(ctx context.Context, args []string) error {
+	args, err := a.parse("cancel", args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := orderID(args[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	order, err := c.CancelOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.print(order, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Cancelled order %d for %s, coins are returned\n", order.ID, orderItem(*order))
+	})
+}
+
+func orderID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: order id must be positive integer, got %q", errUsage, arg)
+	}
+	return id, nil
+}
+
+func orderItem(order client.Order) string {
+	options := client.VariantOptions{Size: order.Size, Color: order.Color}
+	if options == (client.VariantOptions{}) {
+		return order.Item
+	}
+	return fmt.Sprintf("%s (%s)", order.Item, options)
+}
+
+func stockText(stock *int) string {
+	if stock == nil {
+		return "unlimited"
+	}
+	return strconv.Itoa(*stock)
+}
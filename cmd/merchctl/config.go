@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configEnv overrides location of config file.
+const configEnv = "MERCHCTL_CONFIG"
+
+// config is stored between runs, password is never saved...
+type config struct {
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// defaultConfigPath returns $MERCHCTL_CONFIG or merchctl/config.json in user config directory...
+func defaultConfigPath() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".merchctl.json"
+	}
+	return filepath.Join(dir, "merchctl", "config.json")
+}
+
+// loadConfig reads config, missing file gives empty config...
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes config readable only by its owner since it contains token...
+func saveConfig(path string, cfg *config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
@@ -0,0 +1,103 @@
+// Command merchctl is command-line client for merch store.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"merch_store/client"
+)
+
+const usage = `Usage:
+  merchctl [-config file] [-json] <command> [arguments]
+
+Commands:
+  login -username name [-server url]   log in, password is read from $MERCHCTL_PASSWORD or stdin
+  balance                              show coins
+  send <user> <amount>                 send coins to user
+  buy <item> [-size s] [-color c] [-to user]
+                                       buy merch item, variant is chosen by size and color, -to gifts it to colleague
+  history                              show transfers with their ids, refunds and gifts
+  catalog                              show merch and prices
+  orders [-all] [-status s]            show your orders, -all shows orders of all users to store managers
+  order <id> <status>                  move order to packed, handed_over or cancelled, for store managers
+  cancel <id>                          cancel your order that is not handed over yet and get coins back`
+
+// errUsage is returned for wrong arguments, usage is printed for it.
+var errUsage = errors.New("wrong arguments")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout)
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "merchctl: %v\n", err)
+		if errors.Is(err, client.ErrUnauthorized) {
+			fmt.Fprintln(os.Stderr, "run merchctl login to get new token")
+		}
+		os.Exit(1)
+	}
+}
+
+// app holds options shared by all commands...
+type app struct {
+	configPath string
+	json       bool
+	stdin      io.Reader
+	stdout     io.Writer
+}
+
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&a.configPath, "config", a.configPath, "config file")
+	fs.BoolVar(&a.json, "json", a.json, "print JSON")
+	return fs
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	a := &app{configPath: defaultConfigPath(), stdin: stdin, stdout: stdout}
+
+	global := a.flags("merchctl")
+	if err := global.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if global.NArg() == 0 {
+		return errUsage
+	}
+
+	command, args := global.Arg(0), global.Args()[1:]
+	switch command {
+	case "login":
+		return a.login(ctx, args)
+	case "balance":
+		return a.balance(ctx, args)
+	case "send":
+		return a.send(ctx, args)
+	case "buy":
+		return a.buy(ctx, args)
+	case "history":
+		return a.history(ctx, args)
+	case "catalog":
+		return a.catalog(ctx, args)
+	case "orders":
+		return a.orders(ctx, args)
+	case "order":
+		return a.order(ctx, args)
+	case "cancel":
+		return a.cancel(ctx, args)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"merch_store/client"
+	"merch_store/internal/db"
+	"merch_store/internal/handlers"
+	"merch_store/internal/models"
+	"merch_store/internal/seed"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *db.MemoryDatabase) {
+	t.Helper()
+
+	memDB := db.NewMemoryDatabase()
+	_, err := seed.Load(context.Background(), memDB, "", false)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	handlers.NewHandler(memDB).RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, memDB
+}
+
+// merchctl runs command with given stdin and returns its output...
+func merchctl(t *testing.T, configPath, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	args = append([]string{"-config", configPath}, args...)
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout)
+	return stdout.String(), err
+}
+
+func TestMerchctl(t *testing.T) {
+	server, _ := newServer(t)
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	_, err := merchctl(t, configPath, "", "balance")
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	out, err := merchctl(t, configPath, "secret\n", "login", "-server", server.URL, "-username", "alice")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Logged in")
+
+	info, err := os.Stat(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	_, err = merchctl(t, configPath, "secret\n", "login", "-server", server.URL, "-username", "bob")
+	require.NoError(t, err)
+	out, err = merchctl(t, configPath, "secret\n", "login", "-username", "alice")
+	require.NoError(t, err)
+	assert.Contains(t, out, server.URL)
+
+	out, err = merchctl(t, configPath, "", "send", "bob", "100")
+	require.NoError(t, err)
+	assert.Equal(t, "Sent 100 coins to bob\n", out)
+
+	out, err = merchctl(t, configPath, "", "buy", "cup")
+	require.NoError(t, err)
+	assert.Equal(t, "Bought cup\n", out)
+
+	out, err = merchctl(t, configPath, "", "balance")
+	require.NoError(t, err)
+	assert.Equal(t, "880 coins\n", out)
+
+	out, err = merchctl(t, configPath, "", "history")
+	require.NoError(t, err)
+	assert.Regexp(t, `1 +sent to +bob +100`, out)
+
+	out, err = merchctl(t, configPath, "", "buy", "t-shirt", "-size", "XL")
+	require.NoError(t, err)
+	assert.Equal(t, "Bought t-shirt (XL)\n", out)
+	_, err = merchctl(t, configPath, "", "buy", "t-shirt", "-size", "XXS")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	out, err = merchctl(t, configPath, "", "catalog")
+	require.NoError(t, err)
+	assert.Regexp(t, `t-shirt \(XL\) +90 +unlimited +no limit`, out)
+	assert.Regexp(t, `pink-hoody +500 +50 +1 per 91 days`, out)
+
+	out, err = merchctl(t, configPath, "", "catalog", "-json")
+	require.NoError(t, err)
+	var catalog client.CatalogResponse
+	require.NoError(t, json.Unmarshal([]byte(out), &catalog))
+	assert.Len(t, catalog.Items, 10)
+
+	out, err = merchctl(t, configPath, "", "-json", "balance")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"coins": 790}`, out)
+
+	_, err = merchctl(t, configPath, "", "buy", "yacht")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestMerchctl_Orders(t *testing.T) {
+	server, memDB := newServer(t)
+	aliceConfig := filepath.Join(t.TempDir(), "alice.json")
+	managerConfig := filepath.Join(t.TempDir(), "manager.json")
+
+	_, err := merchctl(t, aliceConfig, "secret\n", "login", "-server", server.URL, "-username", "alice")
+	require.NoError(t, err)
+	_, err = merchctl(t, managerConfig, "secret\n", "login", "-server", server.URL, "-username", "manager")
+	require.NoError(t, err)
+	_, err = memDB.SetUserRole(context.Background(), "manager", models.RoleManager)
+	require.NoError(t, err)
+
+	_, err = merchctl(t, aliceConfig, "", "buy", "hoody", "-size", "M", "-color", "black")
+	require.NoError(t, err)
+	_, err = merchctl(t, aliceConfig, "", "buy", "cup")
+	require.NoError(t, err)
+
+	out, err := merchctl(t, aliceConfig, "", "orders")
+	require.NoError(t, err)
+	assert.Regexp(t, `1 +alice +hoody \(M, black\) +placed`, out)
+	assert.Regexp(t, `2 +alice +cup +placed`, out)
+	_, err = merchctl(t, aliceConfig, "", "orders", "-all")
+	assert.ErrorIs(t, err, client.ErrForbidden)
+	_, err = merchctl(t, aliceConfig, "", "order", "1", "packed")
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	out, err = merchctl(t, managerConfig, "", "order", "1", "packed")
+	require.NoError(t, err)
+	assert.Equal(t, "Order 1 of alice for hoody (M, black) is packed\n", out)
+	_, err = merchctl(t, managerConfig, "", "order", "2", "handed_over")
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	out, err = merchctl(t, managerConfig, "", "-json", "orders", "-all", "-status", "placed")
+	require.NoError(t, err)
+	var orders []client.Order
+	require.NoError(t, json.Unmarshal([]byte(out), &orders))
+	if assert.Len(t, orders, 1) {
+		assert.Equal(t, "cup", orders[0].Item)
+	}
+
+	out, err = merchctl(t, aliceConfig, "", "orders", "-status", "packed")
+	require.NoError(t, err)
+	assert.Contains(t, out, "hoody")
+	assert.NotContains(t, out, "cup")
+
+	out, err = merchctl(t, aliceConfig, "", "cancel", "2")
+	require.NoError(t, err)
+	assert.Equal(t, "Cancelled order 2 for cup, coins are returned\n", out)
+	_, err = merchctl(t, aliceConfig, "", "cancel", "2")
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+	_, err = merchctl(t, managerConfig, "", "cancel", "1")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	out, err = merchctl(t, aliceConfig, "", "history")
+	require.NoError(t, err)
+	assert.Regexp(t, `refunded for +cup +20`, out)
+
+	out, err = merchctl(t, aliceConfig, "", "buy", "-to", "manager", "t-shirt", "-size", "S")
+	require.NoError(t, err)
+	assert.Equal(t, "Bought t-shirt (S) for manager\n", out)
+	_, err = merchctl(t, aliceConfig, "", "buy", "cup", "-to", "alice")
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+
+	out, err = merchctl(t, aliceConfig, "", "history")
+	require.NoError(t, err)
+	assert.Regexp(t, `gift to +manager +80 +order 3, t-shirt \(S\)`, out)
+	out, err = merchctl(t, managerConfig, "", "history")
+	require.NoError(t, err)
+	assert.Regexp(t, `gift from +alice +order 3, t-shirt \(S\)`, out)
+	out, err = merchctl(t, managerConfig, "", "orders")
+	require.NoError(t, err)
+	assert.Regexp(t, `3 +alice +t-shirt \(S\) +manager +placed`, out)
+}
+
+func TestMerchctl_Usage(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	for _, args := range [][]string{
+		{},
+		{"dance"},
+		{"send", "bob"},
+		{"send", "bob", "ten"},
+		{"send", "bob", "-5"},
+		{"buy"},
+		{"buy", "cup", "mug"},
+		{"login"},
+		{"orders", "placed"},
+		{"order", "1"},
+		{"order", "first", "packed"},
+		{"cancel"},
+		{"cancel", "0"},
+	} {
+		_, err := merchctl(t, configPath, "", args...)
+		assert.ErrorIs(t, err, errUsage, "args %v", args)
+	}
+}
@@ -2,42 +2,50 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"fmt"
+	"log/slog"
 	"os"
-	"strconv"
 
-	"github.com/gorilla/mux"
-
-	"merch_store/internal/db"
-	"merch_store/internal/handlers"
+	"merch_store/internal/logging"
 )
 
+const usage = `Usage:
+  server [serve]                 run HTTP server
+  server migrate up              apply all pending migrations
+  server migrate down [steps]    revert latest migrations (1 by default)
+  server migrate status          show applied and pending migrations
+  server seed [-file catalog] [-update]
+                                 add missing merch from catalog (built-in one by default),
+                                 -update also overwrites price, limit and price deltas of existing merch`
+
 func main() {
-	port, err := strconv.Atoi(os.Getenv("DB_PORT"))
-	if err != nil {
-		log.Fatalf("Wrong port: %v", err)
-	}
+	logger := logging.New(os.Stdout, slog.LevelInfo)
+	slog.SetDefault(logger)
 
-	db, err := db.NewDatabase(os.Getenv("DB_HOST"), port, os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	command, args := "serve", []string(nil)
+	if len(os.Args) > 1 {
+		command, args = os.Args[1], os.Args[2:]
 	}
-	defer func() {
-		_ = db.Close()
-	}()
 
-	handler := handlers.NewHandler(db)
-	r := mux.NewRouter()
-
-	r.HandleFunc("/api/auth", handler.AuthHandler)
-	r.HandleFunc("/api/info", handler.InfoHandler)
-	r.HandleFunc("/api/sendCoin", handler.SendCoinHandler)
-	r.HandleFunc("/api/buy/{item}", handler.BuyHandler)
+	var err error
+	switch command {
+	case "serve":
+		err = serve(logger)
+	case "migrate":
+		err = migrate(logger, args)
+	case "seed":
+		err = seedCatalog(logger, args)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
 
-	log.Println("Server started on :8080")
-	err = http.ListenAndServe(":8080", r)
 	if err != nil {
-		log.Panicf("Failed in server: %v", err)
+		fatal(logger, command+" failed", err)
 	}
 }
+
+func fatal(logger *slog.Logger, message string, err error) {
+	logger.Error(message, slog.Any("error", err))
+	os.Exit(1)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"merch_store/internal/db"
+)
+
+func migrate(logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate requires one of: up, down, status")
+	}
+
+	ctx := context.Background()
+	known, err := db.EmbeddedMigrations()
+	if err != nil {
+		return err
+	}
+
+	database, err := db.ConnectFromEnv(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, known)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrations are up to date", slog.Int("applied", len(applied)))
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("wrong number of steps %q", args[1])
+			}
+		}
+		reverted, err := database.MigrateDown(ctx, known, steps)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrations reverted", slog.Any("versions", reverted))
+		return nil
+	case "status":
+		statuses, err := database.MigrationsStatus(ctx, known)
+		if err != nil {
+			return err
+		}
+		return printMigrationsStatus(statuses)
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func printMigrationsStatus(statuses []db.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
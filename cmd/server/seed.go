@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+
+	"merch_store/internal/db"
+	"merch_store/internal/seed"
+)
+
+func seedCatalog(logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	file := flags.String("file", "", "path to JSON merch catalog, built-in catalog is used if empty")
+	update := flags.Bool("update", false, "overwrite price, limit and price deltas of existing merch with catalog")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	database, err := db.ConnectFromEnv(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	items, err := seed.Load(ctx, database, *file, *update)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("merch catalog seeded", slog.Int("items", len(items)), slog.Bool("update", *update))
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+
+	"merch_store/internal/db"
+	"merch_store/internal/handlers"
+	"merch_store/internal/idempotency"
+	"merch_store/internal/logging"
+	"merch_store/internal/metrics"
+	"merch_store/internal/openapi"
+	"merch_store/internal/tracing"
+)
+
+const (
+	// readinessDrainPeriod gives load balancers time to notice failing /readyz before listener is closed.
+	readinessDrainPeriod = 3 * time.Second
+	shutdownTimeout      = 5 * time.Second
+)
+
+func serve(logger *slog.Logger) error {
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}()
+
+	database, err := db.ConnectFromEnv(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	metrics.Registry.MustRegister(metrics.NewPoolCollector(database.Pool))
+
+	validationMode, err := openapi.ParseMode(os.Getenv(openapi.ValidationEnv))
+	if err != nil {
+		return err
+	}
+	validator, err := openapi.NewValidator(validationMode)
+	if err != nil {
+		return err
+	}
+
+	handler := handlers.NewHandler(database)
+	r := mux.NewRouter()
+	r.Use(otelmux.Middleware(tracing.ServiceName))
+	r.Use(logging.Middleware(logger))
+	r.Use(metrics.Middleware)
+	r.Use(validator.Middleware)
+	r.Use(idempotency.Middleware(idempotency.NewStore(idempotency.DefaultTTL), handler.Username))
+
+	r.Handle("/metrics", metrics.Handler())
+	r.Handle(openapi.SpecPath, openapi.SpecHandler())
+	r.Handle(openapi.DocsPath, openapi.DocsHandler(openapi.SpecPath))
+	handler.RegisterRoutes(r)
+
+	srv := &http.Server{
+		Addr:              ":8080",
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server started", slog.String("addr", srv.Addr))
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down server")
+	handler.SetShuttingDown()
+	time.Sleep(readinessDrainPeriod)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
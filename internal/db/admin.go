@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"merch_store/internal/models"
+	"merch_store/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReconciliationReason is reason of adjustments recorded by RecordReconciliation.
+const ReconciliationReason = "reconciliation: balance differed from ledger"
+
+// ListUsers returns all users sorted by name, password hashes are not read...
+func (db *Database) ListUsers(ctx context.Context) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "db.ListUsers")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.Pool.Query(ctx, "SELECT id, username, coins, role FROM users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err = rows.Scan(&user.ID, &user.Username, &user.Coins, &user.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// AdjustCoins adds amount, which may be negative, to user balance and records adjustment with reason,
+// it returns updated user...
+func (db *Database) AdjustCoins(ctx context.Context, username string, amount models.Coins, reason string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "db.AdjustCoins")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+	err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, "UPDATE users SET coins = coins + $1 WHERE username = $2 RETURNING id, username, coins, role",
+			amount, username).Scan(&user.ID, &user.Username, &user.Coins, &user.Role)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO coin_adjustments (user_id, amount, reason) VALUES ($1, $2, $3)",
+			user.ID, amount, reason)
+		return err
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// ReverseTransfer sends coins of transfer back from its recipient to sender with compensating transfer linked
+// to original one, it returns the compensating transfer. Transfer is reversed at most once and reversals
+// themselves can't be reversed, ErrInsufficientFunds is returned when recipient has already spent coins...
+func (db *Database) ReverseTransfer(ctx context.Context, transactionID int) (*models.Transaction, error) {
+	ctx, span := tracing.Start(ctx, "db.ReverseTransfer")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var reversal models.Transaction
+	err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		// lock makes concurrent reversals of the same transfer wait for the first one
+		var original models.Transaction
+		var isReversal bool
+		err := tx.QueryRow(ctx, `
+            SELECT from_user_id, to_user_id, amount, reversal_of IS NOT NULL
+            FROM transactions
+            WHERE id = $1
+            FOR UPDATE
+        `, transactionID).Scan(&original.FromUserID, &original.ToUserID, &original.Amount, &isReversal)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("transfer %d: %w", transactionID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if isReversal {
+			return fmt.Errorf("transfer %d is reversal itself: %w", transactionID, ErrNotReversible)
+		}
+
+		var reversed bool
+		err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM transactions WHERE reversal_of = $1)", transactionID).Scan(&reversed)
+		if err != nil {
+			return err
+		}
+		if reversed {
+			return fmt.Errorf("transfer %d is already reversed: %w", transactionID, ErrNotReversible)
+		}
+
+		// users_coins_check fails when recipient doesn't have the coins anymore
+		_, err = tx.Exec(ctx, "UPDATE users SET coins = coins - $1 WHERE id = $2", original.Amount, original.ToUserID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "UPDATE users SET coins = coins + $1 WHERE id = $2", original.Amount, original.FromUserID)
+		if err != nil {
+			return err
+		}
+
+		reversal = models.Transaction{
+			FromUserID: original.ToUserID,
+			ToUserID:   original.FromUserID,
+			Amount:     original.Amount,
+			ReversalOf: transactionID,
+		}
+		return tx.QueryRow(ctx, `
+            INSERT INTO transactions (from_user_id, to_user_id, amount, reversal_of) VALUES ($1, $2, $3, $4)
+            RETURNING id
+        `, reversal.FromUserID, reversal.ToUserID, reversal.Amount, reversal.ReversalOf).Scan(&reversal.ID)
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &reversal, nil
+}
+
+// Ledger returns every balance change of user, or of all users when username is empty, in chronological order...
+func (db *Database) Ledger(ctx context.Context, username string) ([]models.LedgerEntry, error) {
+	ctx, span := tracing.Start(ctx, "db.Ledger")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.Pool.Query(ctx, `
+        SELECT created_at, username, kind, subject, amount, reason FROM (
+            SELECT t.created_at, r.username, $2::TEXT AS kind, s.username AS subject, t.amount,
+                   COALESCE('reversal of transfer ' || t.reversal_of, '') AS reason, t.id AS seq
+            FROM transactions t
+            JOIN users r ON r.id = t.to_user_id
+            JOIN users s ON s.id = t.from_user_id
+            UNION ALL
+            SELECT t.created_at, s.username, $3::TEXT, r.username, -t.amount,
+                   COALESCE('reversal of transfer ' || t.reversal_of, ''), t.id
+            FROM transactions t
+            JOIN users r ON r.id = t.to_user_id
+            JOIN users s ON s.id = t.from_user_id
+            UNION ALL
+            SELECT p.created_at, u.username, $4::TEXT,
+                   m.name || COALESCE(' (' || concat_ws(', ', NULLIF(v.size, ''), NULLIF(v.color, '')) || ')', '')
+                       || COALESCE(' for ' || g.username, ''),
+                   -p.price, '', p.id
+            FROM purchases p
+            JOIN users u ON u.id = p.user_id
+            LEFT JOIN users g ON g.id = p.recipient_id
+            JOIN merch m ON m.id = p.merch_id
+            LEFT JOIN merch_variants v ON v.id = p.variant_id
+            UNION ALL
+            SELECT a.created_at, u.username, $5::TEXT, '', a.amount, a.reason, a.id
+            FROM coin_adjustments a
+            JOIN users u ON u.id = a.user_id
+            UNION ALL
+            SELECT r.created_at, u.username, $6::TEXT,
+                   m.name || COALESCE(' (' || concat_ws(', ', NULLIF(v.size, ''), NULLIF(v.color, '')) || ')', '')
+                       || COALESCE(' for ' || g.username, ''),
+                   r.amount, r.reason, r.id
+            FROM refunds r
+            JOIN purchases p ON p.id = r.purchase_id
+            JOIN users u ON u.id = p.user_id
+            LEFT JOIN users g ON g.id = p.recipient_id
+            JOIN merch m ON m.id = p.merch_id
+            LEFT JOIN merch_variants v ON v.id = p.variant_id
+        ) ledger
+        WHERE $1::TEXT = '' OR username = $1::TEXT
+        ORDER BY created_at, username, kind, seq
+    `, username, models.LedgerTransferIn, models.LedgerTransferOut, models.LedgerPurchase, models.LedgerAdjustment, models.LedgerRefund)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ledger []models.LedgerEntry
+	for rows.Next() {
+		var entry models.LedgerEntry
+		if err = rows.Scan(&entry.Time, &entry.Username, &entry.Kind, &entry.Subject, &entry.Amount, &entry.Reason); err != nil {
+			return nil, err
+		}
+		ledger = append(ledger, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+// SetUserRole changes role of user...
+func (db *Database) SetUserRole(ctx context.Context, username string, role models.Role) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "db.SetUserRole")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+	err := db.Pool.QueryRow(ctx, "UPDATE users SET role = $1 WHERE username = $2 RETURNING id, username, coins, role",
+		role, username).Scan(&user.ID, &user.Username, &user.Coins, &user.Role)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// SetMerchStock sets number of items left, nil stock makes supply unlimited.
+// Empty options change stock of merch itself, otherwise stock of its variant...
+func (db *Database) SetMerchStock(ctx context.Context, name string, options models.VariantOptions, stock *int) (*models.Merch, error) {
+	ctx, span := tracing.Start(ctx, "db.SetMerchStock")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if options == (models.VariantOptions{}) {
+		merch, err := scanMerch(db.Pool.QueryRow(ctx, "UPDATE merch SET stock = $1 WHERE name = $2 RETURNING "+merchColumns, stock, name))
+		if err != nil {
+			return nil, translateError(err)
+		}
+		return merch, nil
+	}
+
+	merch, err := db.GetMerchByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := merch.Variant(options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if _, err = db.Pool.Exec(ctx, "UPDATE merch_variants SET stock = $1 WHERE id = $2", stock, variant.ID); err != nil {
+		return nil, translateError(err)
+	}
+	variant.Stock = stock
+	return merch, nil
+}
+
+// reconcileQuery computes balance every user should have from initial coins and ledger.
+const reconcileQuery = `
+    SELECT u.id, u.username, u.coins, ($1::BIGINT
+        + COALESCE((SELECT SUM(amount) FROM transactions WHERE to_user_id = u.id), 0)
+        - COALESCE((SELECT SUM(amount) FROM transactions WHERE from_user_id = u.id), 0)
+        - COALESCE((SELECT SUM(price) FROM purchases WHERE user_id = u.id), 0)
+        + COALESCE((SELECT SUM(amount) FROM coin_adjustments WHERE user_id = u.id), 0)
+        + COALESCE((SELECT SUM(r.amount) FROM refunds r JOIN purchases p ON p.id = r.purchase_id WHERE p.user_id = u.id), 0)
+    )::BIGINT AS expected
+    FROM users u
+`
+
+// Reconcile returns users whose balance differs from the one computed from ledger...
+func (db *Database) Reconcile(ctx context.Context) ([]models.BalanceMismatch, error) {
+	ctx, span := tracing.Start(ctx, "db.Reconcile")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return reconcile(ctx, db.Pool, "")
+}
+
+// RecordReconciliation records adjustments explaining every mismatch, balances themselves are not changed,
+// it returns mismatches that were recorded...
+func (db *Database) RecordReconciliation(ctx context.Context) ([]models.BalanceMismatch, error) {
+	ctx, span := tracing.Start(ctx, "db.RecordReconciliation")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var mismatches []models.BalanceMismatch
+	err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		var err error
+		// users are locked, so that balances don't change between reading and recording
+		mismatches, err = reconcile(ctx, tx, " FOR UPDATE OF u")
+		if err != nil {
+			return err
+		}
+
+		for _, mismatch := range mismatches {
+			_, err = tx.Exec(ctx, "INSERT INTO coin_adjustments (user_id, amount, reason) VALUES ($1, $2, $3)",
+				mismatch.UserID, mismatch.Coins-mismatch.Expected, ReconciliationReason)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return mismatches, nil
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func reconcile(ctx context.Context, q querier, lock string) ([]models.BalanceMismatch, error) {
+	rows, err := q.Query(ctx, reconcileQuery+" ORDER BY u.username"+lock, models.DefaultCoins)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: %w", err)
+	}
+	defer rows.Close()
+
+	var mismatches []models.BalanceMismatch
+	for rows.Next() {
+		var mismatch models.BalanceMismatch
+		if err = rows.Scan(&mismatch.UserID, &mismatch.Username, &mismatch.Coins, &mismatch.Expected); err != nil {
+			return nil, err
+		}
+		if mismatch.Coins != mismatch.Expected {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mismatches, nil
+}
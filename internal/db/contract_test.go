@@ -0,0 +1,20 @@
+package db_test
+
+import (
+	"testing"
+
+	"merch_store/internal/db"
+	"merch_store/internal/db/dbtest"
+)
+
+func TestContract_Memory(t *testing.T) {
+	dbtest.RunContract(t, func(t *testing.T) db.DB {
+		return db.NewMemoryDatabase()
+	})
+}
+
+func TestContract_Postgres(t *testing.T) {
+	dbtest.RunContract(t, func(t *testing.T) db.DB {
+		return dbtest.NewPostgres(t)
+	})
+}
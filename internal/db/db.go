@@ -5,55 +5,101 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"merch_store/internal/logging"
 	"merch_store/internal/models"
+	"merch_store/internal/tracing"
+	"os"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// DB interface...
+// DefaultQueryTimeout limits how long a single query or transaction may run...
+const DefaultQueryTimeout = 5 * time.Second
+
+// DB interface, implementations report failures with ErrNotFound, ErrInsufficientFunds, ErrOutOfStock,
+// ErrConstraintViolation, ErrConflict, models.ErrCoinsOverflow, models.ErrLimitExceeded and models.ErrStatusTransition...
 type DB interface {
-	GetUserByUsername(username string) (*models.User, error)
-	CreateUser(user *models.User) error
-	TransferCoins(fromUserID, toUserID, amount int) error
-	GetMerchByName(name string) (*models.Merch, error)
-	BuyMerch(userID, merchID, price int) error
-	GetUserInventory(userID int) ([]models.InventoryInfo, error)
-	GetUserTransactions(userID int) (models.CoinHistory, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	TransferCoins(ctx context.Context, fromUserID, toUserID int, amount models.Coins) error
+	GetMerchByName(ctx context.Context, name string) (*models.Merch, error)
+	UpsertMerch(ctx context.Context, items []models.Merch) error
+	InsertMerch(ctx context.Context, items []models.Merch) error
+	ListMerch(ctx context.Context) ([]models.Merch, error)
+	BuyMerch(ctx context.Context, userID, merchID, variantID int, price models.Coins) error
+	GiftMerch(ctx context.Context, buyerID, recipientID, merchID, variantID int, price models.Coins) error
+	GetUserInventory(ctx context.Context, userID int) ([]models.InventoryInfo, error)
+	GetUserTransactions(ctx context.Context, userID int) (models.CoinHistory, error)
+	ListOrders(ctx context.Context, userID int, status models.OrderStatus) ([]models.Order, error)
+	SetOrderStatus(ctx context.Context, orderID int, status models.OrderStatus) (*models.Order, error)
+	RefundOrder(ctx context.Context, orderID int, rules models.RefundRules, reason string) (*models.Order, error)
+	Ping(ctx context.Context) error
+	CheckSchema(ctx context.Context) error
 	Close() error
 }
 
 // Database ...
 type Database struct {
-	Pool *pgxpool.Pool
-	Ctx  context.Context
+	Pool         *pgxpool.Pool
+	QueryTimeout time.Duration
 }
 
 // NewDatabase connects to database...
-func NewDatabase(host string, port int, user string, password string, dbname string) (*Database, error) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%d/%s", user, password, host, port, dbname)
+func NewDatabase(ctx context.Context, host string, port int, user string, password string, dbname string) (*Database, error) {
+	return Connect(ctx, fmt.Sprintf("postgres://%s:%s@%s:%d/%s", user, password, host, port, dbname))
+}
+
+// ConnectFromEnv connects to database configured by DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME...
+func ConnectFromEnv(ctx context.Context) (*Database, error) {
+	port, err := strconv.Atoi(os.Getenv("DB_PORT"))
+	if err != nil {
+		return nil, fmt.Errorf("wrong database port: %w", err)
+	}
+
+	return NewDatabase(ctx, os.Getenv("DB_HOST"), port, os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
+}
 
+// Connect connects to database by connection string, runtime parameters such as search_path may be passed in it...
+func Connect(ctx context.Context, connStr string) (*Database, error) {
 	config, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
-		log.Printf("Failed to parse config: %v", err)
+		logging.FromContext(ctx).Error("failed to parse database config", slog.Any("error", err))
 		return nil, err
 	}
 
-	Ctx := context.Background()
-	Pool, err := pgxpool.NewWithConfig(Ctx, config)
+	config.ConnConfig.Tracer = &tracing.QueryTracer{}
+
+	Pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
-		log.Printf("Failed to connect to database: %v", err)
+		logging.FromContext(ctx).Error("failed to connect to database", slog.Any("error", err))
 		return nil, err
 	}
 
-	err = Pool.Ping(Ctx) // Ping the database to verify the connection
+	pingCtx, cancel := context.WithTimeout(ctx, DefaultQueryTimeout)
+	defer cancel()
+
+	err = Pool.Ping(pingCtx) // Ping the database to verify the connection
 	if err != nil {
-		log.Printf("Failed to ping database: %v", err)
+		logging.FromContext(ctx).Error("failed to ping database", slog.Any("error", err))
+		Pool.Close()
 		return nil, err
 	}
 
-	return &Database{Pool: Pool, Ctx: Ctx}, nil
+	return &Database{Pool: Pool, QueryTimeout: DefaultQueryTimeout}, nil
+}
+
+// withTimeout derives a context bounded by the database query timeout...
+func (db *Database) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.QueryTimeout)
 }
 
 // Close function closes database...
@@ -62,115 +108,446 @@ func (db *Database) Close() error {
 	return nil
 }
 
+// Ping checks that database is reachable...
+func (db *Database) Ping(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "db.Ping")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return db.Pool.Ping(ctx)
+}
+
+// CheckSchema checks that all embedded migrations have been applied...
+func (db *Database) CheckSchema(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "db.CheckSchema")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	known, err := EmbeddedMigrations()
+	if err != nil {
+		return err
+	}
+	if len(known) == 0 {
+		return nil
+	}
+	expected := known[len(known)-1].Version
+
+	var current int
+	err = db.Pool.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	if current < expected {
+		return fmt.Errorf("schema version %d is behind %d", current, expected)
+	}
+	return nil
+}
+
 // GetUserByUsername finds user by name in database...
-func (db *Database) GetUserByUsername(username string) (*models.User, error) {
+func (db *Database) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "db.GetUserByUsername")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	var user models.User
-	err := db.Pool.QueryRow(db.Ctx, "SELECT id, username, password_hash, coins FROM users WHERE username = $1", username).
-		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Coins)
+	err := db.Pool.QueryRow(ctx, "SELECT id, username, password_hash, coins, role FROM users WHERE username = $1", username).
+		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Coins, &user.Role)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	return &user, nil
 }
 
-// CreateUser creates user in database...
-func (db *Database) CreateUser(user *models.User) error {
-	_, err := db.Pool.Exec(db.Ctx, "INSERT INTO users (username,password_hash,coins) VALUES ($1,$2,1000);", user.Username, user.PasswordHash)
+// CreateUser creates user in database with models.DefaultCoins, coins of user are ignored...
+func (db *Database) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "db.CreateUser")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := db.Pool.Exec(ctx, "INSERT INTO users (username,password_hash,coins) VALUES ($1,$2,$3);",
+		user.Username, user.PasswordHash, models.DefaultCoins)
 	if err != nil {
-		log.Printf("Failed to create user: %v", err)
+		err = translateError(err)
+		if !errors.Is(err, ErrConflict) {
+			logging.FromContext(ctx).Error("failed to create user", slog.String("username", user.Username), slog.Any("error", err))
+		}
 		return err
 	}
 	return nil
 }
 
 // TransferCoins implements logic for sending coins from one user to another in database...
-func (db *Database) TransferCoins(fromUserID, toUserID, amount int) error {
-	tx, err := db.Pool.Begin(db.Ctx)
-	if err != nil {
-		return err
-	}
-	defer func() {
+func (db *Database) TransferCoins(ctx context.Context, fromUserID, toUserID int, amount models.Coins) error {
+	ctx, span := tracing.Start(ctx, "db.TransferCoins")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "UPDATE users SET coins = coins - $1 WHERE id = $2", amount, fromUserID)
 		if err != nil {
-			if rb := tx.Rollback(db.Ctx); rb != nil {
-				log.Fatalf("query failed: %v, unable to abort: %v", err, rb)
-			}
-		} else {
-			err = tx.Commit(db.Ctx)
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("sender %d: %w", fromUserID, ErrNotFound)
 		}
-	}()
 
-	_, err = tx.Exec(db.Ctx, "UPDATE users SET coins = coins - $1 WHERE id = $2", amount, fromUserID)
-	if err != nil {
+		tag, err = tx.Exec(ctx, "UPDATE users SET coins = coins + $1 WHERE id = $2", amount, toUserID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("recipient %d: %w", toUserID, ErrNotFound)
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO transactions (from_user_id, to_user_id, amount) VALUES ($1, $2, $3)",
+			fromUserID, toUserID, amount)
 		return err
-	}
+	})
+	return translateError(err)
+}
+
+// merchColumns are columns scanMerch reads.
+const merchColumns = "id, name, price, stock, lifetime_limit, window_limit, window_days"
 
-	_, err = tx.Exec(db.Ctx, "UPDATE users SET coins = coins + $1 WHERE id = $2", amount, toUserID)
+func scanMerch(row pgx.Row) (*models.Merch, error) {
+	var merch models.Merch
+	var lifetime, perWindow, windowDays int
+	err := row.Scan(&merch.ID, &merch.Name, &merch.Price, &merch.Stock, &lifetime, &perWindow, &windowDays)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	merch.Limit = models.NewPurchaseLimit(lifetime, perWindow, windowDays)
+	return &merch, nil
+}
+
+// GetMerchByName finds merch by it's name in database...
+func (db *Database) GetMerchByName(ctx context.Context, name string) (*models.Merch, error) {
+	ctx, span := tracing.Start(ctx, "db.GetMerchByName")
+	defer span.End()
 
-	_, err = tx.Exec(db.Ctx, "INSERT INTO transactions (from_user_id, to_user_id, amount) VALUES ($1, $2, $3)",
-		fromUserID, toUserID, amount)
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	merch, err := scanMerch(db.Pool.QueryRow(ctx, "SELECT "+merchColumns+" FROM merch WHERE name = $1", name))
 	if err != nil {
-		return err
+		return nil, translateError(err)
 	}
 
-	return nil
+	variants, err := db.listVariants(ctx, merch.ID)
+	if err != nil {
+		return nil, err
+	}
+	merch.Variants = variants[merch.ID]
+	return merch, nil
 }
 
-// GetMerchByName finds merch by it's name in database...
-func (db *Database) GetMerchByName(name string) (*models.Merch, error) {
-	var merch models.Merch
-	err := db.Pool.QueryRow(db.Ctx, "SELECT id, name, price FROM merch WHERE name = $1", name).
-		Scan(&merch.ID, &merch.Name, &merch.Price)
+// listVariants returns variants of merch with given id, or of all merch if id is zero, grouped by merch id...
+func (db *Database) listVariants(ctx context.Context, merchID int) (map[int][]models.MerchVariant, error) {
+	rows, err := db.Pool.Query(ctx, `
+        SELECT merch_id, id, size, color, price_delta, stock
+        FROM merch_variants
+        WHERE $1 = 0 OR merch_id = $1
+        ORDER BY id
+    `, merchID)
 	if err != nil {
 		return nil, err
 	}
-	return &merch, nil
+	defer rows.Close()
+
+	variants := make(map[int][]models.MerchVariant)
+	for rows.Next() {
+		var id int
+		var variant models.MerchVariant
+		if err = rows.Scan(&id, &variant.ID, &variant.Size, &variant.Color, &variant.PriceDelta, &variant.Stock); err != nil {
+			return nil, err
+		}
+		variants[id] = append(variants[id], variant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// Queries storing one merch item and one of its variants, upsert ones overwrite price, limit and price delta
+// of existing rows, insert ones keep existing rows as they are. Both never change stock of existing rows.
+const (
+	upsertMerchQuery = `
+        INSERT INTO merch (name, price, stock, lifetime_limit, window_limit, window_days)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (name) DO UPDATE SET
+            price = EXCLUDED.price,
+            lifetime_limit = EXCLUDED.lifetime_limit,
+            window_limit = EXCLUDED.window_limit,
+            window_days = EXCLUDED.window_days
+        RETURNING id
+    `
+	insertMerchQuery = `
+        WITH inserted AS (
+            INSERT INTO merch (name, price, stock, lifetime_limit, window_limit, window_days)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT (name) DO NOTHING
+            RETURNING id
+        )
+        SELECT id FROM inserted
+        UNION ALL
+        SELECT id FROM merch WHERE name = $1
+    `
+	upsertVariantQuery = `
+        INSERT INTO merch_variants (merch_id, size, color, price_delta, stock)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (merch_id, size, color) DO UPDATE SET price_delta = EXCLUDED.price_delta
+    `
+	insertVariantQuery = `
+        INSERT INTO merch_variants (merch_id, size, color, price_delta, stock)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (merch_id, size, color) DO NOTHING
+    `
+)
+
+// UpsertMerch creates merch items and their variants or updates price, limit and price deltas of existing ones,
+// stock is set only for new items and variants, variants missing in items are kept...
+func (db *Database) UpsertMerch(ctx context.Context, items []models.Merch) error {
+	ctx, span := tracing.Start(ctx, "db.UpsertMerch")
+	defer span.End()
+
+	return db.storeMerch(ctx, items, upsertMerchQuery, upsertVariantQuery)
+}
+
+// InsertMerch creates merch items and variants that don't exist yet, existing ones are left untouched...
+func (db *Database) InsertMerch(ctx context.Context, items []models.Merch) error {
+	ctx, span := tracing.Start(ctx, "db.InsertMerch")
+	defer span.End()
+
+	return db.storeMerch(ctx, items, insertMerchQuery, insertVariantQuery)
+}
+
+// storeMerch stores items in one transaction with given merch and variant queries...
+func (db *Database) storeMerch(ctx context.Context, items []models.Merch, merchQuery, variantQuery string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	for _, item := range items {
+		if err := item.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrConstraintViolation, err)
+		}
+	}
+
+	err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		for _, item := range items {
+			var limit models.PurchaseLimit
+			if item.Limit != nil {
+				limit = *item.Limit
+			}
+			var id int
+			err := tx.QueryRow(ctx, merchQuery,
+				item.Name, item.Price, item.Stock, limit.Lifetime, limit.PerWindow, limit.WindowDays).Scan(&id)
+			if err != nil {
+				return err
+			}
+
+			for _, variant := range item.Variants {
+				if _, err = tx.Exec(ctx, variantQuery, id, variant.Size, variant.Color, variant.PriceDelta, variant.Stock); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return translateError(err)
 }
 
-// BuyMerch implements buying merch logic in database...
-func (db *Database) BuyMerch(userID, merchID, price int) error {
-	tx, err := db.Pool.Begin(db.Ctx)
+// ListMerch returns all merch sorted by name...
+func (db *Database) ListMerch(ctx context.Context) ([]models.Merch, error) {
+	ctx, span := tracing.Start(ctx, "db.ListMerch")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.Pool.Query(ctx, "SELECT "+merchColumns+" FROM merch ORDER BY name")
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var catalog []models.Merch
+	for rows.Next() {
+		var merch *models.Merch
+		if merch, err = scanMerch(rows); err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, *merch)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	variants, err := db.listVariants(ctx, 0)
+	if err != nil {
+		return nil, err
 	}
+	for i := range catalog {
+		catalog[i].Variants = variants[catalog[i].ID]
+	}
+
+	return catalog, nil
+}
 
-	defer func() {
+// BuyMerch implements buying merch logic in database, variantID is zero for merch without variants,
+// limited stock of merch and variant is decremented and never goes below zero
+// and purchase limit is checked against purchase history of user without refunded purchases,
+// every purchase places an order...
+func (db *Database) BuyMerch(ctx context.Context, userID, merchID, variantID int, price models.Coins) error {
+	ctx, span := tracing.Start(ctx, "db.BuyMerch")
+	defer span.End()
+
+	return db.buyMerch(ctx, userID, 0, merchID, variantID, price)
+}
+
+// GiftMerch buys merch like BuyMerch does, but item goes to inventory of recipient,
+// gifts count toward purchase limit of buyer...
+func (db *Database) GiftMerch(ctx context.Context, buyerID, recipientID, merchID, variantID int, price models.Coins) error {
+	ctx, span := tracing.Start(ctx, "db.GiftMerch")
+	defer span.End()
+
+	return db.buyMerch(ctx, buyerID, recipientID, merchID, variantID, price)
+}
+
+// buyMerch buys merch for userID, item goes to recipientID unless it's zero...
+func (db *Database) buyMerch(ctx context.Context, userID, recipientID, merchID, variantID int, price models.Coins) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "UPDATE users SET coins = coins - $1 WHERE id = $2", price, userID)
 		if err != nil {
-			if rb := tx.Rollback(db.Ctx); rb != nil {
-				log.Fatalf("query failed: %v, unable to abort: %v", err, rb)
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("user %d: %w", userID, ErrNotFound)
+		}
+
+		if err = takeFromStock(ctx, tx, "merch", "id = $1", merchID); err != nil {
+			return err
+		}
+		if variantID != 0 {
+			if err = takeFromStock(ctx, tx, "merch_variants", "id = $1 AND merch_id = $2", variantID, merchID); err != nil {
+				return err
 			}
 		} else {
-			err = tx.Commit(db.Ctx)
+			var hasVariants bool
+			err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM merch_variants WHERE merch_id = $1)", merchID).Scan(&hasVariants)
+			if err != nil {
+				return err
+			}
+			if hasVariants {
+				return fmt.Errorf("merch %d is sold only as variants: %w", merchID, ErrConstraintViolation)
+			}
+		}
+
+		// purchases of this user are serialized by lock on their row taken above, so counts can't go stale
+		var limit models.PurchaseLimit
+		var total, inWindow int
+		err = tx.QueryRow(ctx, `
+            SELECT m.lifetime_limit, m.window_limit, m.window_days,
+                COUNT(p.id),
+                COUNT(p.id) FILTER (WHERE p.created_at > LOCALTIMESTAMP - make_interval(days => m.window_days))
+            FROM merch m
+            LEFT JOIN purchases p ON p.merch_id = m.id AND p.user_id = $1
+                AND NOT EXISTS (SELECT 1 FROM refunds r WHERE r.purchase_id = p.id)
+            WHERE m.id = $2
+            GROUP BY m.id
+        `, userID, merchID).Scan(&limit.Lifetime, &limit.PerWindow, &limit.WindowDays, &total, &inWindow)
+		if err != nil {
+			return err
+		}
+		if err = limit.Check(total, inWindow); err != nil {
+			return fmt.Errorf("merch %d: %w", merchID, err)
 		}
-	}()
 
-	_, err = tx.Exec(db.Ctx, "UPDATE users SET coins = coins - $1 WHERE id = $2", price, userID)
+		owner := userID
+		if recipientID != 0 {
+			owner = recipientID
+		}
+		_, err = tx.Exec(ctx, `
+           INSERT INTO inventory (user_id, merch_id, variant_id, quantity)
+           VALUES ($1, $2, NULLIF($3, 0), 1)
+           ON CONFLICT (user_id, merch_id, (COALESCE(variant_id, 0))) DO UPDATE
+           SET quantity = inventory.quantity + 1
+       `, owner, merchID, variantID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+            WITH purchase AS (
+                INSERT INTO purchases (user_id, recipient_id, merch_id, variant_id, price)
+                VALUES ($1, NULLIF($2, 0), $3, NULLIF($4, 0), $5)
+                RETURNING id
+            )
+            INSERT INTO orders (purchase_id) SELECT id FROM purchase
+        `, userID, recipientID, merchID, variantID, price)
+		return err
+	})
+	return translateError(err)
+}
+
+// takeFromStock decrements limited stock of row in table matching where, it returns ErrOutOfStock when nothing is left
+// and ErrConstraintViolation when there is no such row...
+func takeFromStock(ctx context.Context, tx pgx.Tx, table, where string, args ...any) error {
+	// row lock taken by UPDATE makes concurrent buyers re-check stock after previous purchase commits
+	tag, err := tx.Exec(ctx, "UPDATE "+table+" SET stock = stock - 1 WHERE "+where+" AND stock > 0", args...)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
 
-	_, err = tx.Exec(db.Ctx, `
-       INSERT INTO inventory (user_id, merch_id, quantity)
-       VALUES ($1, $2, 1)
-       ON CONFLICT (user_id, merch_id) DO UPDATE
-       SET quantity = inventory.quantity + 1
-   `, userID, merchID)
+	var unlimited bool
+	err = tx.QueryRow(ctx, "SELECT stock IS NULL FROM "+table+" WHERE "+where, args...).Scan(&unlimited)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s %v: %w", table, args, ErrConstraintViolation)
+	}
 	if err != nil {
 		return err
 	}
-
+	if !unlimited {
+		return fmt.Errorf("%s %v: %w", table, args, ErrOutOfStock)
+	}
 	return nil
 }
 
 // GetUserInventory gets user inventory from database...
-func (db *Database) GetUserInventory(userID int) ([]models.InventoryInfo, error) {
-	rows, err := db.Pool.Query(db.Ctx, `
-        SELECT m.name, i.quantity
+func (db *Database) GetUserInventory(ctx context.Context, userID int) ([]models.InventoryInfo, error) {
+	ctx, span := tracing.Start(ctx, "db.GetUserInventory")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.Pool.Query(ctx, `
+        SELECT m.name, COALESCE(v.size, ''), COALESCE(v.color, ''), i.quantity
         FROM inventory i
         JOIN merch m ON i.merch_id = m.id
+        LEFT JOIN merch_variants v ON i.variant_id = v.id
         WHERE i.user_id = $1
+        ORDER BY m.name, v.id NULLS FIRST
     `, userID)
 
 	if err != nil {
@@ -181,7 +558,7 @@ func (db *Database) GetUserInventory(userID int) ([]models.InventoryInfo, error)
 	var inventory []models.InventoryInfo
 	for rows.Next() {
 		var item models.InventoryInfo
-		err = rows.Scan(&item.Type, &item.Quantity)
+		err = rows.Scan(&item.Type, &item.Size, &item.Color, &item.Quantity)
 		if err != nil {
 			return nil, err
 		}
@@ -195,15 +572,25 @@ func (db *Database) GetUserInventory(userID int) ([]models.InventoryInfo, error)
 	return inventory, nil
 }
 
-// GetUserTransactions gets user transactions from database...
-func (db *Database) GetUserTransactions(userID int) (models.CoinHistory, error) {
+// reversalColumns link transfer t with its reversal, zero means there is no link.
+const reversalColumns = `COALESCE(t.reversal_of, 0), COALESCE((SELECT r.id FROM transactions r WHERE r.reversal_of = t.id), 0)`
+
+// GetUserTransactions gets user transactions, refunds and gifts from database...
+func (db *Database) GetUserTransactions(ctx context.Context, userID int) (models.CoinHistory, error) {
+	ctx, span := tracing.Start(ctx, "db.GetUserTransactions")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	var history models.CoinHistory
 
-	rows, err := db.Pool.Query(db.Ctx, `
-        SELECT u.username, t.amount
+	rows, err := db.Pool.Query(ctx, `
+        SELECT t.id, u.username, t.amount, `+reversalColumns+`
         FROM transactions t
         JOIN users u ON t.from_user_id = u.id
         WHERE t.to_user_id = $1
+        ORDER BY t.id
    `, userID)
 
 	if err != nil {
@@ -213,7 +600,7 @@ func (db *Database) GetUserTransactions(userID int) (models.CoinHistory, error)
 
 	for rows.Next() {
 		var transaction models.TransactionInfo
-		err = rows.Scan(&transaction.Username, &transaction.Amount)
+		err = rows.Scan(&transaction.ID, &transaction.Username, &transaction.Amount, &transaction.ReversalOf, &transaction.ReversedBy)
 		if err != nil {
 			return history, err
 		}
@@ -224,11 +611,12 @@ func (db *Database) GetUserTransactions(userID int) (models.CoinHistory, error)
 		return history, err
 	}
 
-	rows, err = db.Pool.Query(db.Ctx, `
-        SELECT u.username, t.amount
+	rows, err = db.Pool.Query(ctx, `
+        SELECT t.id, u.username, t.amount, `+reversalColumns+`
         FROM transactions t
         JOIN users u ON t.to_user_id = u.id
         WHERE t.from_user_id = $1
+        ORDER BY t.id
     `, userID)
 
 	if err != nil {
@@ -238,7 +626,7 @@ func (db *Database) GetUserTransactions(userID int) (models.CoinHistory, error)
 
 	for rows.Next() {
 		var transaction models.TransactionInfo
-		err = rows.Scan(&transaction.Username, &transaction.Amount)
+		err = rows.Scan(&transaction.ID, &transaction.Username, &transaction.Amount, &transaction.ReversalOf, &transaction.ReversedBy)
 		if err != nil {
 			return history, err
 		}
@@ -249,5 +637,70 @@ func (db *Database) GetUserTransactions(userID int) (models.CoinHistory, error)
 		return history, err
 	}
 
+	rows, err = db.Pool.Query(ctx, `
+        SELECT p.id, m.name, COALESCE(v.size, ''), COALESCE(v.color, ''), r.amount, r.reason
+        FROM refunds r
+        JOIN purchases p ON p.id = r.purchase_id
+        JOIN merch m ON m.id = p.merch_id
+        LEFT JOIN merch_variants v ON v.id = p.variant_id
+        WHERE p.user_id = $1
+        ORDER BY r.id
+    `, userID)
+
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var refund models.RefundInfo
+		err = rows.Scan(&refund.OrderID, &refund.Item, &refund.Size, &refund.Color, &refund.Amount, &refund.Reason)
+		if err != nil {
+			return history, err
+		}
+		history.Refunds = append(history.Refunds, refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		return history, err
+	}
+
+	rows, err = db.Pool.Query(ctx, `
+        SELECT p.id, p.user_id = $1, CASE WHEN p.user_id = $1 THEN g.username ELSE u.username END,
+            m.name, COALESCE(v.size, ''), COALESCE(v.color, ''), p.price,
+            EXISTS (SELECT 1 FROM refunds r WHERE r.purchase_id = p.id)
+        FROM purchases p
+        JOIN users u ON u.id = p.user_id
+        JOIN users g ON g.id = p.recipient_id
+        JOIN merch m ON m.id = p.merch_id
+        LEFT JOIN merch_variants v ON v.id = p.variant_id
+        WHERE p.user_id = $1 OR p.recipient_id = $1
+        ORDER BY p.id
+    `, userID)
+
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gift models.GiftInfo
+		var sent bool
+		err = rows.Scan(&gift.OrderID, &sent, &gift.Username, &gift.Item, &gift.Size, &gift.Color, &gift.Amount, &gift.Refunded)
+		if err != nil {
+			return history, err
+		}
+		if sent {
+			history.GiftsSent = append(history.GiftsSent, gift)
+		} else {
+			gift.Amount = 0
+			history.GiftsReceived = append(history.GiftsReceived, gift)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return history, err
+	}
+
 	return history, nil
 }
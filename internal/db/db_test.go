@@ -1,134 +1,168 @@
 package db
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"merch_store/internal/db/pgtest"
 	"merch_store/internal/models"
+	"merch_store/internal/seed"
 	"os"
 	"testing"
+	"testing/fstest"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var testDB *Database
+var ctx = context.Background()
 
 func TestMain(m *testing.M) {
-	SetupTestDB(&testDB)
-	code := m.Run()
+	os.Exit(pgtest.Run(m))
+}
 
-	ClearDatabase(testDB)
-	_ = testDB.Close()
-	os.Exit(code)
+// newTestDB returns database in its own schema with migrations applied and default catalog loaded,
+// it mirrors dbtest.NewPostgres which can't be imported from here...
+func newTestDB(t *testing.T) *Database {
+	t.Helper()
+
+	testDB, err := Connect(ctx, pgtest.NewSchema(t))
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(func() { _ = testDB.Close() })
+
+	known, err := EmbeddedMigrations()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err = testDB.MigrateUp(ctx, known); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	if _, err = seed.Load(ctx, testDB, "", false); err != nil {
+		t.Fatalf("load catalog: %v", err)
+	}
+	return testDB
 }
 
 func TestGetUserByUsername(t *testing.T) {
-	ClearDatabase(testDB)
+	t.Parallel()
+	testDB := newTestDB(t)
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('testuser', $1, 100)", hashedPassword)
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('testuser', $1, 100)", hashedPassword)
 	assert.NoError(t, err)
 
-	user, err := testDB.GetUserByUsername("testuser")
+	user, err := testDB.GetUserByUsername(ctx, "testuser")
 	assert.NoError(t, err)
 
 	assert.Equal(t, "testuser", user.Username)
-	assert.Equal(t, 100, user.Coins)
+	assert.Equal(t, models.Coins(100), user.Coins)
 }
 
 func TestGetUserByUsername_NotFound(t *testing.T) {
-	ClearDatabase(testDB)
+	t.Parallel()
+	testDB := newTestDB(t)
 
-	user, err := testDB.GetUserByUsername("nonexistent")
-	assert.Error(t, err)
+	user, err := testDB.GetUserByUsername(ctx, "nonexistent")
+	assert.ErrorIs(t, err, ErrNotFound)
 	assert.Nil(t, user)
 }
 
 func TestCreateUser(t *testing.T) {
-	ClearDatabase(testDB)
+	t.Parallel()
+	testDB := newTestDB(t)
 
 	user := &models.User{Username: "newuser", PasswordHash: "hash", Coins: 500}
-	err := testDB.CreateUser(user)
+	err := testDB.CreateUser(ctx, user)
 	assert.NoError(t, err)
 
-	retrievedUser, err := testDB.GetUserByUsername("newuser")
+	retrievedUser, err := testDB.GetUserByUsername(ctx, "newuser")
 	assert.NoError(t, err)
 	assert.Equal(t, "newuser", retrievedUser.Username)
-	assert.Equal(t, 1000, retrievedUser.Coins)
+	assert.Equal(t, models.Coins(1000), retrievedUser.Coins)
 }
 
 func TestTransferCoins(t *testing.T) {
-	ClearDatabase(testDB)
+	t.Parallel()
+	testDB := newTestDB(t)
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('user1', $1, 100)", hashedPassword)
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('user1', $1, 100)", hashedPassword)
 	assert.NoError(t, err)
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('user2', $1, 50)", hashedPassword)
+	_, err = testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('user2', $1, 50)", hashedPassword)
 	assert.NoError(t, err)
 
-	user1, _ := testDB.GetUserByUsername("user1")
-	user2, _ := testDB.GetUserByUsername("user2")
+	user1, _ := testDB.GetUserByUsername(ctx, "user1")
+	user2, _ := testDB.GetUserByUsername(ctx, "user2")
 
-	err = testDB.TransferCoins(user1.ID, user2.ID, 30)
+	err = testDB.TransferCoins(ctx, user1.ID, user2.ID, 30)
 	assert.NoError(t, err)
 
-	updatedUser1, _ := testDB.GetUserByUsername("user1")
-	updatedUser2, _ := testDB.GetUserByUsername("user2")
+	updatedUser1, _ := testDB.GetUserByUsername(ctx, "user1")
+	updatedUser2, _ := testDB.GetUserByUsername(ctx, "user2")
 
-	assert.Equal(t, 70, updatedUser1.Coins)
-	assert.Equal(t, 80, updatedUser2.Coins)
+	assert.Equal(t, models.Coins(70), updatedUser1.Coins)
+	assert.Equal(t, models.Coins(80), updatedUser2.Coins)
 }
 
 func TestGetMerchByName(t *testing.T) {
-	ClearDatabase(testDB)
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO merch (name, price) VALUES ('special-item', 150)")
+	t.Parallel()
+	testDB := newTestDB(t)
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO merch (name, price) VALUES ('special-item', 150)")
 	assert.NoError(t, err)
 
-	merch, err := testDB.GetMerchByName("special-item")
+	merch, err := testDB.GetMerchByName(ctx, "special-item")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "special-item", merch.Name)
-	assert.Equal(t, 150, merch.Price)
+	assert.Equal(t, models.Coins(150), merch.Price)
 }
 
 func TestBuyMerch(t *testing.T) {
-	ClearDatabase(testDB)
+	t.Parallel()
+	testDB := newTestDB(t)
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('buyer', $1, 200)", hashedPassword)
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('buyer', $1, 200)", hashedPassword)
 	assert.NoError(t, err)
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO merch (name, price) VALUES ('fancy-item', 120)")
+	_, err = testDB.Pool.Exec(ctx, "INSERT INTO merch (name, price) VALUES ('fancy-item', 120)")
 	assert.NoError(t, err)
 
-	buyer, _ := testDB.GetUserByUsername("buyer")
-	merch, _ := testDB.GetMerchByName("fancy-item")
+	buyer, _ := testDB.GetUserByUsername(ctx, "buyer")
+	merch, _ := testDB.GetMerchByName(ctx, "fancy-item")
 
-	err = testDB.BuyMerch(buyer.ID, merch.ID, merch.Price)
+	err = testDB.BuyMerch(ctx, buyer.ID, merch.ID, 0, merch.Price)
 	assert.NoError(t, err)
 
-	inventory, _ := testDB.GetUserInventory(buyer.ID)
+	inventory, _ := testDB.GetUserInventory(ctx, buyer.ID)
 	assert.Len(t, inventory, 1)
 	assert.Equal(t, "fancy-item", inventory[0].Type)
 	assert.Equal(t, 1, inventory[0].Quantity)
 
-	updatedBuyer, _ := testDB.GetUserByUsername("buyer")
-	assert.Equal(t, 80, updatedBuyer.Coins)
+	updatedBuyer, _ := testDB.GetUserByUsername(ctx, "buyer")
+	assert.Equal(t, models.Coins(80), updatedBuyer.Coins)
 }
 
 func TestGetUserInventory(t *testing.T) {
-	ClearDatabase(testDB)
+	t.Parallel()
+	testDB := newTestDB(t)
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
 
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('inventory_user', $1, 100)", hashedPassword)
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('inventory_user', $1, 100)", hashedPassword)
 	assert.NoError(t, err)
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO merch (name, price) VALUES ('inventory_item', 50)")
+	_, err = testDB.Pool.Exec(ctx, "INSERT INTO merch (name, price) VALUES ('inventory_item', 50)")
 	assert.NoError(t, err)
 
-	user, _ := testDB.GetUserByUsername("inventory_user")
-	merch, _ := testDB.GetMerchByName("inventory_item")
+	user, _ := testDB.GetUserByUsername(ctx, "inventory_user")
+	merch, _ := testDB.GetMerchByName(ctx, "inventory_item")
 
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO inventory (user_id, merch_id, quantity) VALUES ($1, $2, 3)", user.ID, merch.ID)
+	_, err = testDB.Pool.Exec(ctx, "INSERT INTO inventory (user_id, merch_id, quantity) VALUES ($1, $2, 3)", user.ID, merch.ID)
 	assert.NoError(t, err)
 
-	inventory, err := testDB.GetUserInventory(user.ID)
+	inventory, err := testDB.GetUserInventory(ctx, user.ID)
 	assert.NoError(t, err)
 	assert.Len(t, inventory, 1)
 	assert.Equal(t, "inventory_item", inventory[0].Type)
@@ -136,29 +170,595 @@ func TestGetUserInventory(t *testing.T) {
 }
 
 func TestGetUserTransactions(t *testing.T) {
-	ClearDatabase(testDB)
+	t.Parallel()
+	testDB := newTestDB(t)
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
 
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('sender', $1, 100)", hashedPassword)
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('sender', $1, 100)", hashedPassword)
 	assert.NoError(t, err)
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('receiver', $1, 50)", hashedPassword)
+	_, err = testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('receiver', $1, 50)", hashedPassword)
 	assert.NoError(t, err)
 
-	sender, _ := testDB.GetUserByUsername("sender")
-	receiver, _ := testDB.GetUserByUsername("receiver")
+	sender, _ := testDB.GetUserByUsername(ctx, "sender")
+	receiver, _ := testDB.GetUserByUsername(ctx, "receiver")
 
-	err = testDB.TransferCoins(sender.ID, receiver.ID, 25)
+	err = testDB.TransferCoins(ctx, sender.ID, receiver.ID, 25)
 	assert.NoError(t, err)
 
-	history, err := testDB.GetUserTransactions(sender.ID)
+	history, err := testDB.GetUserTransactions(ctx, sender.ID)
 	assert.NoError(t, err)
 	assert.Len(t, history.Sent, 1)
 	assert.Equal(t, "receiver", history.Sent[0].Username)
-	assert.Equal(t, 25, history.Sent[0].Amount)
+	assert.Equal(t, models.Coins(25), history.Sent[0].Amount)
 
-	history, err = testDB.GetUserTransactions(receiver.ID)
+	history, err = testDB.GetUserTransactions(ctx, receiver.ID)
 	assert.NoError(t, err)
 	assert.Len(t, history.Received, 1)
 	assert.Equal(t, "sender", history.Received[0].Username)
-	assert.Equal(t, 25, history.Received[0].Amount)
+	assert.Equal(t, models.Coins(25), history.Received[0].Amount)
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.Ping(ctx))
+}
+
+func TestCheckSchema(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CheckSchema(ctx))
+}
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	errFailed := errors.New("failed inside transaction")
+	err := testDB.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('rolled_back', 'hash', 100)")
+		assert.NoError(t, err)
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	_, err = testDB.GetUserByUsername(ctx, "rolled_back")
+	assert.Error(t, err)
+}
+
+func TestWithTx_CommitsOnSuccess(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	err := testDB.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('committed', 'hash', 100)")
+		return err
+	})
+	assert.NoError(t, err)
+
+	user, err := testDB.GetUserByUsername(ctx, "committed")
+	assert.NoError(t, err)
+	assert.Equal(t, "committed", user.Username)
+}
+
+func TestWithTx_RetriesSerializationFailures(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	attempts := 0
+	err := testDB.withTx(ctx, pgx.TxOptions{}, func(_ pgx.Tx) error {
+		attempts++
+		if attempts < maxTxAttempts {
+			return &pgconn.PgError{Code: serializationFailureCode}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, maxTxAttempts, attempts)
+}
+
+func TestWithTx_DoesNotRetryOtherErrors(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	attempts := 0
+	err := testDB.withTx(ctx, pgx.TxOptions{}, func(_ pgx.Tx) error {
+		attempts++
+		return &pgconn.PgError{Code: "23514"}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("CREATE TABLE second ();")},
+		"0002_second.down.sql": {Data: []byte("DROP TABLE second;")},
+		"0001_first.up.sql":    {Data: []byte("CREATE TABLE first ();")},
+		"0001_first.down.sql":  {Data: []byte("DROP TABLE first;")},
+		"migrations.go":        {Data: []byte("package migrations")},
+	}
+
+	loaded, err := LoadMigrations(fsys)
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, 1, loaded[0].Version)
+	assert.Equal(t, "first", loaded[0].Name)
+	assert.Equal(t, "DROP TABLE second;", loaded[1].Down)
+}
+
+func TestLoadMigrations_MissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_first.up.sql": {Data: []byte("CREATE TABLE first ();")},
+	}
+
+	_, err := LoadMigrations(fsys)
+	assert.Error(t, err)
+}
+
+func TestMigrationsStatus_AllApplied(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	known, err := EmbeddedMigrations()
+	assert.NoError(t, err)
+
+	statuses, err := testDB.MigrationsStatus(ctx, known)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, len(known))
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "migration %d should be applied", status.Version)
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	known, err := EmbeddedMigrations()
+	assert.NoError(t, err)
+
+	reverted, err := testDB.MigrateDown(ctx, known, len(known))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(known))
+	assert.Error(t, testDB.CheckSchema(ctx))
+
+	applied, err := testDB.MigrateUp(ctx, known)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(known))
+	assert.NoError(t, testDB.CheckSchema(ctx))
+}
+
+func TestUpsertMerch(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	err := testDB.UpsertMerch(ctx, []models.Merch{{Name: "cup", Price: 25}, {Name: "sticker", Price: 5}})
+	assert.NoError(t, err)
+
+	cup, err := testDB.GetMerchByName(ctx, "cup")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Coins(25), cup.Price)
+
+	sticker, err := testDB.GetMerchByName(ctx, "sticker")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Coins(5), sticker.Price)
+}
+
+// TestSeed_MatchesCatalog checks that merch of fresh database comes from catalog only, not from migrations...
+func TestSeed_MatchesCatalog(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	catalog, err := seed.DefaultCatalog()
+	assert.NoError(t, err)
+	stored, err := testDB.ListMerch(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, stored, len(catalog))
+
+	for _, item := range catalog {
+		merch, err := testDB.GetMerchByName(ctx, item.Name)
+		if !assert.NoError(t, err, item.Name) {
+			continue
+		}
+		assert.Equal(t, item.Price, merch.Price, item.Name)
+		assert.Equal(t, item.Stock, merch.Stock, item.Name)
+		assert.Equal(t, item.Limit, merch.Limit, item.Name)
+		assert.Len(t, merch.Variants, len(item.Variants), item.Name)
+	}
+}
+
+func TestTransferCoins_BigintAmounts(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	const rich = models.Coins(1) << 40
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('rich', 'hash', $1), ('poor', 'hash', 0)", rich)
+	assert.NoError(t, err)
+
+	richUser, _ := testDB.GetUserByUsername(ctx, "rich")
+	poorUser, _ := testDB.GetUserByUsername(ctx, "poor")
+	assert.Equal(t, rich, richUser.Coins)
+
+	err = testDB.TransferCoins(ctx, richUser.ID, poorUser.ID, rich-1)
+	assert.NoError(t, err)
+
+	updatedPoor, _ := testDB.GetUserByUsername(ctx, "poor")
+	assert.Equal(t, rich-1, updatedPoor.Coins)
+}
+
+func TestCreateUser_Duplicate(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	user := &models.User{Username: "twice", PasswordHash: "hash"}
+	assert.NoError(t, testDB.CreateUser(ctx, user))
+	assert.ErrorIs(t, testDB.CreateUser(ctx, user), ErrConflict)
+}
+
+func TestTransferCoins_InsufficientFunds(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('user1', 'hash', 10), ('user2', 'hash', 0)")
+	assert.NoError(t, err)
+
+	user1, _ := testDB.GetUserByUsername(ctx, "user1")
+	user2, _ := testDB.GetUserByUsername(ctx, "user2")
+
+	err = testDB.TransferCoins(ctx, user1.ID, user2.ID, 11)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedUser2, _ := testDB.GetUserByUsername(ctx, "user2")
+	assert.Equal(t, models.Coins(0), updatedUser2.Coins)
+}
+
+func TestTransferCoins_UnknownRecipient(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('user1', 'hash', 10)")
+	assert.NoError(t, err)
+	user1, _ := testDB.GetUserByUsername(ctx, "user1")
+
+	err = testDB.TransferCoins(ctx, user1.ID, -1, 5)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	updatedUser1, _ := testDB.GetUserByUsername(ctx, "user1")
+	assert.Equal(t, models.Coins(10), updatedUser1.Coins)
+}
+
+func TestBuyMerch_InsufficientFunds(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	_, err := testDB.Pool.Exec(ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('buyer', 'hash', 10)")
+	assert.NoError(t, err)
+	buyer, _ := testDB.GetUserByUsername(ctx, "buyer")
+	merch, _ := testDB.GetMerchByName(ctx, "pink-hoody")
+
+	err = testDB.BuyMerch(ctx, buyer.ID, merch.ID, 0, merch.Price)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	inventory, _ := testDB.GetUserInventory(ctx, buyer.ID)
+	assert.Empty(t, inventory)
+}
+
+func TestTranslateError(t *testing.T) {
+	assert.NoError(t, translateError(nil))
+	assert.ErrorIs(t, translateError(pgx.ErrNoRows), ErrNotFound)
+	assert.ErrorIs(t, translateError(&pgconn.PgError{Code: checkViolationCode, ConstraintName: usersCoinsCheck}), ErrInsufficientFunds)
+	assert.ErrorIs(t, translateError(&pgconn.PgError{Code: checkViolationCode, ConstraintName: "merch_price_check"}), ErrConstraintViolation)
+	assert.ErrorIs(t, translateError(&pgconn.PgError{Code: foreignKeyViolationCode}), ErrConstraintViolation)
+	assert.ErrorIs(t, translateError(&pgconn.PgError{Code: uniqueViolationCode}), ErrConflict)
+	assert.ErrorIs(t, translateError(&pgconn.PgError{Code: numericValueOutOfRangeCode}), models.ErrCoinsOverflow)
+
+	outage := errors.New("connection refused")
+	translated := translateError(outage)
+	assert.Equal(t, outage, translated)
+	assert.NotErrorIs(t, translated, ErrNotFound)
+}
+
+func TestAdjustCoins(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+
+	user, err := testDB.AdjustCoins(ctx, "alice", -300, "refund of broken cup")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Coins(700), user.Coins)
+
+	_, err = testDB.AdjustCoins(ctx, "alice", -701, "too much")
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	_, err = testDB.AdjustCoins(ctx, "nobody", 10, "bonus")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = testDB.AdjustCoins(ctx, "alice", 10, "")
+	assert.ErrorIs(t, err, ErrConstraintViolation)
+
+	ledger, err := testDB.Ledger(ctx, "alice")
+	assert.NoError(t, err)
+	if assert.Len(t, ledger, 1) {
+		assert.Equal(t, models.LedgerAdjustment, ledger[0].Kind)
+		assert.Equal(t, models.Coins(-300), ledger[0].Amount)
+		assert.Equal(t, "refund of broken cup", ledger[0].Reason)
+	}
+}
+
+func TestReverseTransfer(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}))
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "carol", PasswordHash: "hash"}))
+	alice, _ := testDB.GetUserByUsername(ctx, "alice")
+	bob, _ := testDB.GetUserByUsername(ctx, "bob")
+	carol, _ := testDB.GetUserByUsername(ctx, "carol")
+
+	assert.NoError(t, testDB.TransferCoins(ctx, alice.ID, bob.ID, 100))
+	history, err := testDB.GetUserTransactions(ctx, alice.ID)
+	assert.NoError(t, err)
+	original := history.Sent[0].ID
+
+	reversal, err := testDB.ReverseTransfer(ctx, original)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Transaction{ID: reversal.ID, FromUserID: bob.ID, ToUserID: alice.ID, Amount: 100, ReversalOf: original}, *reversal)
+
+	updated, _ := testDB.GetUserByUsername(ctx, "alice")
+	assert.Equal(t, models.Coins(1000), updated.Coins)
+	updated, _ = testDB.GetUserByUsername(ctx, "bob")
+	assert.Equal(t, models.Coins(1000), updated.Coins)
+
+	history, err = testDB.GetUserTransactions(ctx, alice.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.TransactionInfo{{ID: original, Username: "bob", Amount: 100, ReversedBy: reversal.ID}}, history.Sent)
+	assert.Equal(t, []models.TransactionInfo{{ID: reversal.ID, Username: "bob", Amount: 100, ReversalOf: original}}, history.Received)
+	history, err = testDB.GetUserTransactions(ctx, bob.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.TransactionInfo{{ID: original, Username: "alice", Amount: 100, ReversedBy: reversal.ID}}, history.Received)
+	assert.Equal(t, []models.TransactionInfo{{ID: reversal.ID, Username: "alice", Amount: 100, ReversalOf: original}}, history.Sent)
+
+	_, err = testDB.ReverseTransfer(ctx, original)
+	assert.ErrorIs(t, err, ErrNotReversible)
+	_, err = testDB.ReverseTransfer(ctx, reversal.ID)
+	assert.ErrorIs(t, err, ErrNotReversible)
+	_, err = testDB.ReverseTransfer(ctx, reversal.ID+100)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ledger, err := testDB.Ledger(ctx, "alice")
+	assert.NoError(t, err)
+	if assert.Len(t, ledger, 2) {
+		assert.Equal(t, "", ledger[0].Reason)
+		assert.Equal(t, models.LedgerTransferIn, ledger[1].Kind)
+		assert.Equal(t, fmt.Sprintf("reversal of transfer %d", original), ledger[1].Reason)
+	}
+	mismatches, err := testDB.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	// bob spent received coins, so they can't be taken back
+	assert.NoError(t, testDB.TransferCoins(ctx, alice.ID, bob.ID, 100))
+	assert.NoError(t, testDB.TransferCoins(ctx, bob.ID, carol.ID, 1050))
+	history, err = testDB.GetUserTransactions(ctx, alice.ID)
+	assert.NoError(t, err)
+	_, err = testDB.ReverseTransfer(ctx, history.Sent[1].ID)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updated, _ = testDB.GetUserByUsername(ctx, "alice")
+	assert.Equal(t, models.Coins(900), updated.Coins)
+	updated, _ = testDB.GetUserByUsername(ctx, "bob")
+	assert.Equal(t, models.Coins(50), updated.Coins)
+	history, err = testDB.GetUserTransactions(ctx, bob.ID)
+	assert.NoError(t, err)
+	assert.Len(t, history.Sent, 2)
+}
+
+func TestLedger(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}))
+	alice, _ := testDB.GetUserByUsername(ctx, "alice")
+	bob, _ := testDB.GetUserByUsername(ctx, "bob")
+	cup, _ := testDB.GetMerchByName(ctx, "cup")
+
+	assert.NoError(t, testDB.TransferCoins(ctx, alice.ID, bob.ID, 100))
+	assert.NoError(t, testDB.BuyMerch(ctx, bob.ID, cup.ID, 0, cup.Price))
+
+	ledger, err := testDB.Ledger(ctx, "bob")
+	assert.NoError(t, err)
+	if assert.Len(t, ledger, 2) {
+		assert.Equal(t, models.LedgerEntry{Time: ledger[0].Time, Username: "bob", Kind: models.LedgerTransferIn, Subject: "alice", Amount: 100}, ledger[0])
+		assert.Equal(t, models.LedgerEntry{Time: ledger[1].Time, Username: "bob", Kind: models.LedgerPurchase, Subject: "cup", Amount: -cup.Price}, ledger[1])
+	}
+
+	all, err := testDB.Ledger(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	var total models.Coins
+	for _, entry := range all {
+		total += entry.Amount
+	}
+	assert.Equal(t, -cup.Price, total)
+
+	hoody, _ := testDB.GetMerchByName(ctx, "hoody")
+	variant, err := hoody.Variant(models.VariantOptions{Size: "M", Color: "black"})
+	if assert.NoError(t, err) {
+		assert.NoError(t, testDB.BuyMerch(ctx, alice.ID, hoody.ID, variant.ID, hoody.Price))
+		ledger, err = testDB.Ledger(ctx, "alice")
+		assert.NoError(t, err)
+		if assert.Len(t, ledger, 2) {
+			assert.Equal(t, "hoody (M, black)", ledger[1].Subject)
+		}
+	}
+}
+
+func TestLedger_Gifts(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}))
+	alice, _ := testDB.GetUserByUsername(ctx, "alice")
+	bob, _ := testDB.GetUserByUsername(ctx, "bob")
+	cup, _ := testDB.GetMerchByName(ctx, "cup")
+
+	assert.NoError(t, testDB.GiftMerch(ctx, alice.ID, bob.ID, cup.ID, 0, cup.Price))
+	orders, err := testDB.ListOrders(ctx, bob.ID, "")
+	assert.NoError(t, err)
+	if assert.Len(t, orders, 1) {
+		_, err = testDB.RefundOrder(ctx, orders[0].ID, models.RefundRules{}, "wrong colleague")
+		assert.NoError(t, err)
+	}
+
+	ledger, err := testDB.Ledger(ctx, "alice")
+	assert.NoError(t, err)
+	if assert.Len(t, ledger, 2) {
+		assert.Equal(t, "cup for bob", ledger[0].Subject)
+		assert.Equal(t, -cup.Price, ledger[0].Amount)
+		assert.Equal(t, "cup for bob", ledger[1].Subject)
+		assert.Equal(t, cup.Price, ledger[1].Amount)
+	}
+	ledger, err = testDB.Ledger(ctx, "bob")
+	assert.NoError(t, err)
+	assert.Empty(t, ledger)
+
+	mismatches, err := testDB.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}))
+	alice, _ := testDB.GetUserByUsername(ctx, "alice")
+	bob, _ := testDB.GetUserByUsername(ctx, "bob")
+	assert.NoError(t, testDB.TransferCoins(ctx, alice.ID, bob.ID, 100))
+	_, err := testDB.AdjustCoins(ctx, "bob", 50, "bonus")
+	assert.NoError(t, err)
+
+	mismatches, err := testDB.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	// balance changed by hand, without ledger entry
+	_, err = testDB.Pool.Exec(ctx, "UPDATE users SET coins = coins + 7 WHERE username = 'alice'")
+	assert.NoError(t, err)
+
+	mismatches, err = testDB.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.BalanceMismatch{{UserID: alice.ID, Username: "alice", Coins: 907, Expected: 900}}, mismatches)
+
+	recorded, err := testDB.RecordReconciliation(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, mismatches, recorded)
+
+	mismatches, err = testDB.Reconcile(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	updated, _ := testDB.GetUserByUsername(ctx, "alice")
+	assert.Equal(t, models.Coins(907), updated.Coins)
+}
+
+func TestListUsers(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}))
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+
+	users, err := testDB.ListUsers(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "alice", users[0].Username)
+		assert.Equal(t, "bob", users[1].Username)
+		assert.Empty(t, users[0].PasswordHash)
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+
+	user, err := testDB.SetUserRole(ctx, "alice", models.RoleManager)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleManager, user.Role)
+	user, err = testDB.GetUserByUsername(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleManager, user.Role)
+
+	_, err = testDB.SetUserRole(ctx, "alice", "admin")
+	assert.ErrorIs(t, err, ErrConstraintViolation)
+	_, err = testDB.SetUserRole(ctx, "nobody", models.RoleManager)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSetMerchStock(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	stock := 5
+	merch, err := testDB.SetMerchStock(ctx, "cup", models.VariantOptions{}, &stock)
+	assert.NoError(t, err)
+	if assert.NotNil(t, merch.Stock) {
+		assert.Equal(t, 5, *merch.Stock)
+	}
+
+	merch, err = testDB.SetMerchStock(ctx, "cup", models.VariantOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, merch.Stock)
+
+	negative := -1
+	_, err = testDB.SetMerchStock(ctx, "cup", models.VariantOptions{}, &negative)
+	assert.Error(t, err)
+	_, err = testDB.SetMerchStock(ctx, "yacht", models.VariantOptions{}, &stock)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	merch, err = testDB.SetMerchStock(ctx, "t-shirt", models.VariantOptions{Size: "M"}, &stock)
+	assert.NoError(t, err)
+	variant, err := merch.Variant(models.VariantOptions{Size: "M"})
+	if assert.NoError(t, err) && assert.NotNil(t, variant.Stock) {
+		assert.Equal(t, 5, *variant.Stock)
+	}
+	merch, err = testDB.GetMerchByName(ctx, "t-shirt")
+	assert.NoError(t, err)
+	variant, _ = merch.Variant(models.VariantOptions{Size: "M"})
+	if assert.NotNil(t, variant) && assert.NotNil(t, variant.Stock) {
+		assert.Equal(t, 5, *variant.Stock)
+	}
+	_, err = testDB.SetMerchStock(ctx, "t-shirt", models.VariantOptions{Size: "XXL"}, &stock)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestBuyMerch_WindowLimitExpires(t *testing.T) {
+	t.Parallel()
+	testDB := newTestDB(t)
+
+	assert.NoError(t, testDB.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}))
+	assert.NoError(t, testDB.UpsertMerch(ctx, []models.Merch{{Name: "cup", Price: 20, Limit: models.NewPurchaseLimit(2, 1, 91)}}))
+	alice, _ := testDB.GetUserByUsername(ctx, "alice")
+	cup, _ := testDB.GetMerchByName(ctx, "cup")
+
+	assert.NoError(t, testDB.BuyMerch(ctx, alice.ID, cup.ID, 0, cup.Price))
+	assert.ErrorIs(t, testDB.BuyMerch(ctx, alice.ID, cup.ID, 0, cup.Price), models.ErrLimitExceeded)
+
+	_, err := testDB.Pool.Exec(ctx, "UPDATE purchases SET created_at = created_at - INTERVAL '92 days'")
+	assert.NoError(t, err)
+	assert.NoError(t, testDB.BuyMerch(ctx, alice.ID, cup.ID, 0, cup.Price))
+
+	// lifetime limit still counts purchases outside of window
+	_, err = testDB.Pool.Exec(ctx, "UPDATE purchases SET created_at = created_at - INTERVAL '92 days'")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, testDB.BuyMerch(ctx, alice.ID, cup.ID, 0, cup.Price), models.ErrLimitExceeded)
 }
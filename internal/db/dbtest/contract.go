@@ -0,0 +1,996 @@
+// Package dbtest contains contract tests every db.DB implementation must pass.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"merch_store/internal/db"
+	"merch_store/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunContract runs contract tests against database returned by newDB, newDB must return database without users for every call...
+func RunContract(t *testing.T, newDB func(t *testing.T) db.DB) {
+	cases := map[string]func(t *testing.T, store db.DB){
+		"CreateUser":                      testCreateUser,
+		"CreateUser_Duplicate":            testCreateUserDuplicate,
+		"GetUserByUsername_NotFound":      testGetUserNotFound,
+		"TransferCoins":                   testTransferCoins,
+		"TransferCoins_InsufficientFunds": testTransferInsufficientFunds,
+		"TransferCoins_UnknownUsers":      testTransferUnknownUsers,
+		"TransferCoins_NonPositive":       testTransferNonPositive,
+		"TransferCoins_Concurrent":        testTransferConcurrent,
+		"ReverseTransfer":                 testReverseTransfer,
+		"UpsertMerch":                     testUpsertMerch,
+		"UpsertMerch_InvalidPrice":        testUpsertMerchInvalidPrice,
+		"UpsertMerch_KeepsStock":          testUpsertMerchKeepsStock,
+		"UpsertMerch_Limit":               testUpsertMerchLimit,
+		"UpsertMerch_Variants":            testUpsertMerchVariants,
+		"InsertMerch":                     testInsertMerch,
+		"GetMerchByName_NotFound":         testGetMerchNotFound,
+		"ListMerch":                       testListMerch,
+		"BuyMerch":                        testBuyMerch,
+		"BuyMerch_InsufficientFunds":      testBuyMerchInsufficientFunds,
+		"BuyMerch_UnknownUser":            testBuyMerchUnknownUser,
+		"BuyMerch_OutOfStock":             testBuyMerchOutOfStock,
+		"BuyMerch_ConcurrentStock":        testBuyMerchConcurrentStock,
+		"BuyMerch_LifetimeLimit":          testBuyMerchLifetimeLimit,
+		"BuyMerch_WindowLimit":            testBuyMerchWindowLimit,
+		"BuyMerch_ConcurrentLimit":        testBuyMerchConcurrentLimit,
+		"BuyMerch_Variant":                testBuyMerchVariant,
+		"BuyMerch_VariantOutOfStock":      testBuyMerchVariantOutOfStock,
+		"BuyMerch_ForeignVariant":         testBuyMerchForeignVariant,
+		"ListOrders":                      testListOrders,
+		"SetOrderStatus":                  testSetOrderStatus,
+		"SetOrderStatus_Concurrent":       testSetOrderStatusConcurrent,
+		"SetOrderStatus_CancelRefunds":    testSetOrderStatusCancelRefunds,
+		"RefundOrder":                     testRefundOrder,
+		"RefundOrder_Rules":               testRefundOrderRules,
+		"RefundOrder_Concurrent":          testRefundOrderConcurrent,
+		"RefundOrder_FreesLimit":          testRefundOrderFreesLimit,
+		"GiftMerch":                       testGiftMerch,
+		"GiftMerch_Rules":                 testGiftMerchRules,
+		"GiftMerch_Refund":                testGiftMerchRefund,
+		"Health":                          testHealth,
+	}
+
+	for name, run := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			run(t, newDB(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, store db.DB, username string) *models.User {
+	t.Helper()
+
+	require.NoError(t, store.CreateUser(context.Background(), &models.User{Username: username, PasswordHash: "hash"}))
+	user, err := store.GetUserByUsername(context.Background(), username)
+	require.NoError(t, err)
+	return user
+}
+
+func balance(t *testing.T, store db.DB, username string) models.Coins {
+	t.Helper()
+
+	user, err := store.GetUserByUsername(context.Background(), username)
+	require.NoError(t, err)
+	return user.Coins
+}
+
+func createMerch(t *testing.T, store db.DB, name string, price models.Coins) *models.Merch {
+	t.Helper()
+
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: name, Price: price}}))
+	merch, err := store.GetMerchByName(context.Background(), name)
+	require.NoError(t, err)
+	return merch
+}
+
+func testCreateUser(t *testing.T, store db.DB) {
+	err := store.CreateUser(context.Background(), &models.User{Username: "alice", PasswordHash: "hash", Coins: 5})
+	require.NoError(t, err)
+
+	user, err := store.GetUserByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "hash", user.PasswordHash)
+	assert.Equal(t, models.DefaultCoins, user.Coins)
+	assert.Equal(t, models.RoleEmployee, user.Role)
+}
+
+func testCreateUserDuplicate(t *testing.T, store db.DB) {
+	createUser(t, store, "alice")
+
+	err := store.CreateUser(context.Background(), &models.User{Username: "alice", PasswordHash: "other"})
+	assert.ErrorIs(t, err, db.ErrConflict)
+
+	user, err := store.GetUserByUsername(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", user.PasswordHash)
+}
+
+func testGetUserNotFound(t *testing.T, store db.DB) {
+	user, err := store.GetUserByUsername(context.Background(), "nobody")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.Nil(t, user)
+}
+
+func testTransferCoins(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+
+	require.NoError(t, store.TransferCoins(context.Background(), alice.ID, bob.ID, 300))
+	require.NoError(t, store.TransferCoins(context.Background(), bob.ID, alice.ID, 100))
+
+	assert.Equal(t, models.Coins(800), balance(t, store, "alice"))
+	assert.Equal(t, models.Coins(1200), balance(t, store, "bob"))
+
+	history, err := store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TransactionInfo{{ID: 1, Username: "bob", Amount: 300}}, history.Sent)
+	assert.Equal(t, []models.TransactionInfo{{ID: 2, Username: "bob", Amount: 100}}, history.Received)
+}
+
+func testTransferInsufficientFunds(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+
+	err := store.TransferCoins(context.Background(), alice.ID, bob.ID, 1001)
+	assert.ErrorIs(t, err, db.ErrInsufficientFunds)
+
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+	assert.Equal(t, models.Coins(1000), balance(t, store, "bob"))
+
+	history, err := store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, history.Sent)
+}
+
+func testTransferUnknownUsers(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+
+	err := store.TransferCoins(context.Background(), alice.ID, alice.ID+1000, 10)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+
+	err = store.TransferCoins(context.Background(), alice.ID+1000, alice.ID, 10)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+}
+
+func testTransferNonPositive(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+
+	assert.ErrorIs(t, store.TransferCoins(context.Background(), alice.ID, bob.ID, 0), db.ErrConstraintViolation)
+	assert.Error(t, store.TransferCoins(context.Background(), alice.ID, bob.ID, -10))
+	assert.Error(t, store.TransferCoins(context.Background(), alice.ID, bob.ID, math.MinInt64))
+
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+	assert.Equal(t, models.Coins(1000), balance(t, store, "bob"))
+}
+
+// transferReverser is implemented by databases that let admins reverse transfers...
+type transferReverser interface {
+	ReverseTransfer(ctx context.Context, transactionID int) (*models.Transaction, error)
+}
+
+func testReverseTransfer(t *testing.T, store db.DB) {
+	reverser, ok := store.(transferReverser)
+	require.True(t, ok, "%T can't reverse transfers", store)
+
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+	require.NoError(t, store.TransferCoins(context.Background(), alice.ID, bob.ID, 100))
+	history, err := store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	original := history.Sent[0].ID
+
+	reversal, err := reverser.ReverseTransfer(context.Background(), original)
+	require.NoError(t, err)
+	assert.Equal(t, models.Transaction{ID: reversal.ID, FromUserID: bob.ID, ToUserID: alice.ID, Amount: 100, ReversalOf: original}, *reversal)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+	assert.Equal(t, models.Coins(1000), balance(t, store, "bob"))
+
+	history, err = store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TransactionInfo{{ID: original, Username: "bob", Amount: 100, ReversedBy: reversal.ID}}, history.Sent)
+	assert.Equal(t, []models.TransactionInfo{{ID: reversal.ID, Username: "bob", Amount: 100, ReversalOf: original}}, history.Received)
+	history, err = store.GetUserTransactions(context.Background(), bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TransactionInfo{{ID: original, Username: "alice", Amount: 100, ReversedBy: reversal.ID}}, history.Received)
+	assert.Equal(t, []models.TransactionInfo{{ID: reversal.ID, Username: "alice", Amount: 100, ReversalOf: original}}, history.Sent)
+
+	_, err = reverser.ReverseTransfer(context.Background(), original)
+	assert.ErrorIs(t, err, db.ErrNotReversible)
+	_, err = reverser.ReverseTransfer(context.Background(), reversal.ID)
+	assert.ErrorIs(t, err, db.ErrNotReversible)
+	_, err = reverser.ReverseTransfer(context.Background(), reversal.ID+100)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// bob spends received coins, so they can't be taken back
+	carol := createUser(t, store, "carol")
+	require.NoError(t, store.TransferCoins(context.Background(), alice.ID, bob.ID, 100))
+	require.NoError(t, store.TransferCoins(context.Background(), bob.ID, carol.ID, 1050))
+	history, err = store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	_, err = reverser.ReverseTransfer(context.Background(), history.Sent[1].ID)
+	assert.ErrorIs(t, err, db.ErrInsufficientFunds)
+	assert.Equal(t, models.Coins(900), balance(t, store, "alice"))
+	assert.Equal(t, models.Coins(50), balance(t, store, "bob"))
+}
+
+func testTransferConcurrent(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+
+	// 30 transfers of 50 coins are more than alice has, exactly 20 of them must succeed
+	const transfers, amount = 30, 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, rejected := 0, 0
+	for i := 0; i < transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.TransferCoins(context.Background(), alice.ID, bob.ID, amount)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else if assert.ErrorIs(t, err, db.ErrInsufficientFunds) {
+				rejected++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 20, succeeded)
+	assert.Equal(t, 10, rejected)
+	assert.Equal(t, models.Coins(0), balance(t, store, "alice"))
+	assert.Equal(t, models.Coins(2000), balance(t, store, "bob"))
+
+	history, err := store.GetUserTransactions(context.Background(), bob.ID)
+	require.NoError(t, err)
+	assert.Len(t, history.Received, succeeded)
+}
+
+func testUpsertMerch(t *testing.T, store db.DB) {
+	created := createMerch(t, store, "sticker", 5)
+	assert.NotZero(t, created.ID)
+
+	updated := createMerch(t, store, "sticker", 7)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, models.Coins(7), updated.Price)
+}
+
+func testUpsertMerchInvalidPrice(t *testing.T, store db.DB) {
+	err := store.UpsertMerch(context.Background(), []models.Merch{{Name: "sticker", Price: 5}, {Name: "free", Price: 0}})
+	assert.ErrorIs(t, err, db.ErrConstraintViolation)
+
+	_, err = store.GetMerchByName(context.Background(), "sticker")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func testUpsertMerchKeepsStock(t *testing.T, store db.DB) {
+	stock := 3
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: "pink-cap", Price: 100, Stock: &stock}}))
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: "pink-cap", Price: 120}}))
+
+	merch, err := store.GetMerchByName(context.Background(), "pink-cap")
+	require.NoError(t, err)
+	assert.Equal(t, models.Coins(120), merch.Price)
+	if assert.NotNil(t, merch.Stock) {
+		assert.Equal(t, 3, *merch.Stock)
+	}
+
+	negative := -1
+	err = store.UpsertMerch(context.Background(), []models.Merch{{Name: "broken", Price: 10, Stock: &negative}})
+	assert.ErrorIs(t, err, db.ErrConstraintViolation)
+}
+
+func testUpsertMerchLimit(t *testing.T, store db.DB) {
+	limit := models.NewPurchaseLimit(0, 1, 91)
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: "pink-cap", Price: 100, Limit: limit}}))
+
+	merch, err := store.GetMerchByName(context.Background(), "pink-cap")
+	require.NoError(t, err)
+	assert.Equal(t, limit, merch.Limit)
+
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: "pink-cap", Price: 100}}))
+	merch, err = store.GetMerchByName(context.Background(), "pink-cap")
+	require.NoError(t, err)
+	assert.Nil(t, merch.Limit)
+
+	err = store.UpsertMerch(context.Background(), []models.Merch{{Name: "broken", Price: 10, Limit: &models.PurchaseLimit{PerWindow: 1}}})
+	assert.ErrorIs(t, err, db.ErrConstraintViolation)
+}
+
+func testInsertMerch(t *testing.T, store db.DB) {
+	stock := 3
+	limit := models.NewPurchaseLimit(0, 1, 91)
+	shirt := createShirt(t, store, "test-shirt", models.MerchVariant{VariantOptions: size("XL"), PriceDelta: 10})
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: "pink-cap", Price: 100, Stock: &stock, Limit: limit}}))
+
+	require.NoError(t, store.InsertMerch(context.Background(), []models.Merch{
+		{Name: "pink-cap", Price: 120},
+		{Name: "test-shirt", Price: 90, Variants: []models.MerchVariant{
+			{VariantOptions: size("XL"), PriceDelta: 20},
+			{VariantOptions: size("M")},
+		}},
+		{Name: "sticker", Price: 5, Limit: limit},
+	}))
+
+	pinkCap, err := store.GetMerchByName(context.Background(), "pink-cap")
+	require.NoError(t, err)
+	assert.Equal(t, models.Coins(100), pinkCap.Price)
+	assert.Equal(t, limit, pinkCap.Limit)
+	if assert.NotNil(t, pinkCap.Stock) {
+		assert.Equal(t, 3, *pinkCap.Stock)
+	}
+
+	updated, err := store.GetMerchByName(context.Background(), "test-shirt")
+	require.NoError(t, err)
+	assert.Equal(t, models.Coins(80), updated.Price)
+	if assert.Len(t, updated.Variants, 2) {
+		assert.Equal(t, shirt.Variants[0], updated.Variants[0])
+		assert.Equal(t, size("M"), updated.Variants[1].VariantOptions)
+	}
+
+	sticker, err := store.GetMerchByName(context.Background(), "sticker")
+	require.NoError(t, err)
+	assert.Equal(t, models.Coins(5), sticker.Price)
+	assert.Equal(t, limit, sticker.Limit)
+}
+
+func testGetMerchNotFound(t *testing.T, store db.DB) {
+	merch, err := store.GetMerchByName(context.Background(), "nothing")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.Nil(t, merch)
+}
+
+func testListMerch(t *testing.T, store db.DB) {
+	before, err := store.ListMerch(context.Background())
+	require.NoError(t, err)
+
+	created := createMerch(t, store, "aaa-sticker", 5)
+
+	catalog, err := store.ListMerch(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, catalog, len(before)+1)
+	assert.Contains(t, catalog, *created)
+	assert.IsIncreasing(t, names(catalog))
+}
+
+func names(catalog []models.Merch) []string {
+	result := make([]string, 0, len(catalog))
+	for _, merch := range catalog {
+		result = append(result, merch.Name)
+	}
+	return result
+}
+
+func testBuyMerch(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	cup := createMerch(t, store, "cup", 20)
+	pen := createMerch(t, store, "pen", 10)
+
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price))
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price))
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, pen.ID, 0, pen.Price))
+
+	assert.Equal(t, models.Coins(950), balance(t, store, "alice"))
+
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.InventoryInfo{{Type: "cup", Quantity: 2}, {Type: "pen", Quantity: 1}}, inventory)
+}
+
+func testBuyMerchInsufficientFunds(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	hoody := createMerch(t, store, "golden-hoody", 1001)
+
+	err := store.BuyMerch(context.Background(), alice.ID, hoody.ID, 0, hoody.Price)
+	assert.ErrorIs(t, err, db.ErrInsufficientFunds)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, inventory)
+}
+
+func testBuyMerchUnknownUser(t *testing.T, store db.DB) {
+	cup := createMerch(t, store, "cup", 20)
+
+	err := store.BuyMerch(context.Background(), 1_000_000, cup.ID, 0, cup.Price)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func createLimitedMerch(t *testing.T, store db.DB, name string, price models.Coins, stock int) *models.Merch {
+	t.Helper()
+
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: name, Price: price, Stock: &stock}}))
+	merch, err := store.GetMerchByName(context.Background(), name)
+	require.NoError(t, err)
+	return merch
+}
+
+func stockOf(t *testing.T, store db.DB, name string) int {
+	t.Helper()
+
+	merch, err := store.GetMerchByName(context.Background(), name)
+	require.NoError(t, err)
+	require.NotNil(t, merch.Stock)
+	return *merch.Stock
+}
+
+func testBuyMerchOutOfStock(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	limited := createLimitedMerch(t, store, "pink-cap", 100, 1)
+
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, limited.ID, 0, limited.Price))
+	assert.Equal(t, 0, stockOf(t, store, "pink-cap"))
+
+	err := store.BuyMerch(context.Background(), alice.ID, limited.ID, 0, limited.Price)
+	assert.ErrorIs(t, err, db.ErrOutOfStock)
+	assert.Equal(t, models.Coins(900), balance(t, store, "alice"))
+	assert.Equal(t, 0, stockOf(t, store, "pink-cap"))
+
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryInfo{{Type: "pink-cap", Quantity: 1}}, inventory)
+}
+
+func testBuyMerchConcurrentStock(t *testing.T, store db.DB) {
+	// 20 buyers compete for 5 items, every buyer can afford it
+	const buyers, stock = 20, 5
+	limited := createLimitedMerch(t, store, "pink-cap", 10, stock)
+	users := make([]*models.User, buyers)
+	for i := range users {
+		users[i] = createUser(t, store, fmt.Sprintf("buyer-%d", i))
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, rejected := 0, 0
+	for _, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.BuyMerch(context.Background(), user.ID, limited.ID, 0, limited.Price)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else if assert.ErrorIs(t, err, db.ErrOutOfStock) {
+				rejected++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, stock, succeeded)
+	assert.Equal(t, buyers-stock, rejected)
+	assert.Equal(t, 0, stockOf(t, store, "pink-cap"))
+}
+
+func createLimitedPerUser(t *testing.T, store db.DB, name string, limit *models.PurchaseLimit) *models.Merch {
+	t.Helper()
+
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: name, Price: 10, Limit: limit}}))
+	merch, err := store.GetMerchByName(context.Background(), name)
+	require.NoError(t, err)
+	return merch
+}
+
+func testBuyMerchLifetimeLimit(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+	badge := createLimitedPerUser(t, store, "badge", models.NewPurchaseLimit(2, 0, 0))
+
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, badge.ID, 0, badge.Price))
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, badge.ID, 0, badge.Price))
+	err := store.BuyMerch(context.Background(), alice.ID, badge.ID, 0, badge.Price)
+	assert.ErrorIs(t, err, models.ErrLimitExceeded)
+	assert.Equal(t, models.Coins(980), balance(t, store, "alice"))
+
+	// limit is per user
+	assert.NoError(t, store.BuyMerch(context.Background(), bob.ID, badge.ID, 0, badge.Price))
+}
+
+func testBuyMerchWindowLimit(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	hoody := createLimitedPerUser(t, store, "quarterly-hoody", models.NewPurchaseLimit(0, 1, 91))
+
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, hoody.ID, 0, hoody.Price))
+	err := store.BuyMerch(context.Background(), alice.ID, hoody.ID, 0, hoody.Price)
+	assert.ErrorIs(t, err, models.ErrLimitExceeded)
+	assert.Equal(t, models.Coins(990), balance(t, store, "alice"))
+
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryInfo{{Type: "quarterly-hoody", Quantity: 1}}, inventory)
+}
+
+func testBuyMerchConcurrentLimit(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	badge := createLimitedPerUser(t, store, "badge", models.NewPurchaseLimit(3, 0, 0))
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.BuyMerch(context.Background(), alice.ID, badge.ID, 0, badge.Price)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, models.ErrLimitExceeded)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, succeeded)
+	assert.Equal(t, models.Coins(970), balance(t, store, "alice"))
+}
+
+func createShirt(t *testing.T, store db.DB, name string, variants ...models.MerchVariant) *models.Merch {
+	t.Helper()
+
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: name, Price: 80, Variants: variants}}))
+	merch, err := store.GetMerchByName(context.Background(), name)
+	require.NoError(t, err)
+	require.Len(t, merch.Variants, len(variants))
+	return merch
+}
+
+func size(size string) models.VariantOptions {
+	return models.VariantOptions{Size: size}
+}
+
+func testUpsertMerchVariants(t *testing.T, store db.DB) {
+	stock := 3
+	shirt := createShirt(t, store, "test-shirt",
+		models.MerchVariant{VariantOptions: size("M")},
+		models.MerchVariant{VariantOptions: size("XL"), PriceDelta: 10, Stock: &stock})
+	assert.NotZero(t, shirt.Variants[0].ID)
+	assert.Equal(t, size("M"), shirt.Variants[0].VariantOptions)
+	assert.Equal(t, models.Coins(10), shirt.Variants[1].PriceDelta)
+	assert.Equal(t, &stock, shirt.Variants[1].Stock)
+
+	// price delta is updated, stock is kept, variants missing in update are kept
+	err := store.UpsertMerch(context.Background(), []models.Merch{{Name: "test-shirt", Price: 80, Variants: []models.MerchVariant{
+		{VariantOptions: size("XL"), PriceDelta: 20},
+		{VariantOptions: models.VariantOptions{Size: "S", Color: "pink"}},
+	}}})
+	require.NoError(t, err)
+
+	updated, err := store.GetMerchByName(context.Background(), "test-shirt")
+	require.NoError(t, err)
+	require.Len(t, updated.Variants, 3)
+	assert.Equal(t, shirt.Variants[0], updated.Variants[0])
+	assert.Equal(t, shirt.Variants[1].ID, updated.Variants[1].ID)
+	assert.Equal(t, models.Coins(20), updated.Variants[1].PriceDelta)
+	assert.Equal(t, &stock, updated.Variants[1].Stock)
+	assert.Equal(t, models.VariantOptions{Size: "S", Color: "pink"}, updated.Variants[2].VariantOptions)
+
+	catalog, err := store.ListMerch(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, catalog, *updated)
+
+	err = store.UpsertMerch(context.Background(), []models.Merch{{Name: "broken", Price: 10, Variants: []models.MerchVariant{
+		{VariantOptions: size("M"), PriceDelta: -10},
+	}}})
+	assert.ErrorIs(t, err, db.ErrConstraintViolation)
+}
+
+func testBuyMerchVariant(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	shirt := createShirt(t, store, "test-shirt",
+		models.MerchVariant{VariantOptions: size("M")},
+		models.MerchVariant{VariantOptions: size("XL"), PriceDelta: 10})
+	medium, large := shirt.Variants[0], shirt.Variants[1]
+
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, shirt.ID, large.ID, 90))
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, shirt.ID, medium.ID, 80))
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, shirt.ID, large.ID, 90))
+	err := store.BuyMerch(context.Background(), alice.ID, shirt.ID, 0, 80)
+	assert.ErrorIs(t, err, db.ErrConstraintViolation)
+	assert.Equal(t, models.Coins(740), balance(t, store, "alice"))
+
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryInfo{
+		{Type: "test-shirt", Size: "M", Quantity: 1},
+		{Type: "test-shirt", Size: "XL", Quantity: 2},
+	}, inventory)
+}
+
+func testBuyMerchVariantOutOfStock(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	one := 1
+	shirt := createShirt(t, store, "test-shirt",
+		models.MerchVariant{VariantOptions: size("M"), Stock: &one},
+		models.MerchVariant{VariantOptions: size("L")})
+	medium, large := shirt.Variants[0], shirt.Variants[1]
+
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, shirt.ID, medium.ID, shirt.Price))
+	err := store.BuyMerch(context.Background(), alice.ID, shirt.ID, medium.ID, shirt.Price)
+	assert.ErrorIs(t, err, db.ErrOutOfStock)
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, shirt.ID, large.ID, shirt.Price))
+	assert.Equal(t, models.Coins(840), balance(t, store, "alice"))
+
+	updated, err := store.GetMerchByName(context.Background(), "test-shirt")
+	require.NoError(t, err)
+	assert.Equal(t, 0, *updated.Variants[0].Stock)
+}
+
+func testBuyMerchForeignVariant(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	shirt := createShirt(t, store, "test-shirt", models.MerchVariant{VariantOptions: size("M")})
+	cup := createMerch(t, store, "test-cup", 20)
+
+	err := store.BuyMerch(context.Background(), alice.ID, cup.ID, shirt.Variants[0].ID, cup.Price)
+	assert.ErrorIs(t, err, db.ErrConstraintViolation)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+}
+
+func testListOrders(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+	shirt := createShirt(t, store, "test-shirt", models.MerchVariant{VariantOptions: size("M")})
+	cup := createMerch(t, store, "test-cup", 20)
+
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, shirt.ID, shirt.Variants[0].ID, shirt.Price))
+	require.NoError(t, store.BuyMerch(context.Background(), bob.ID, cup.ID, 0, cup.Price))
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price))
+
+	orders, err := store.ListOrders(context.Background(), 0, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 3)
+	assert.NotZero(t, orders[0].ID)
+	assert.False(t, orders[0].CreatedAt.IsZero())
+	assert.Equal(t, orders[0].CreatedAt, orders[0].UpdatedAt)
+	assert.Equal(t, models.Order{
+		ID:        orders[0].ID,
+		Username:  "alice",
+		Item:      "test-shirt",
+		Size:      "M",
+		Status:    models.OrderPlaced,
+		CreatedAt: orders[0].CreatedAt,
+		UpdatedAt: orders[0].UpdatedAt,
+	}, orders[0])
+	assert.Equal(t, "bob", orders[1].Username)
+	assert.Equal(t, "test-cup", orders[2].Item)
+
+	orders, err = store.ListOrders(context.Background(), alice.ID, "")
+	require.NoError(t, err)
+	assert.Len(t, orders, 2)
+
+	_, err = store.SetOrderStatus(context.Background(), orders[1].ID, models.OrderPacked)
+	require.NoError(t, err)
+	packed, err := store.ListOrders(context.Background(), 0, models.OrderPacked)
+	require.NoError(t, err)
+	if assert.Len(t, packed, 1) {
+		assert.Equal(t, orders[1].ID, packed[0].ID)
+	}
+	placed, err := store.ListOrders(context.Background(), alice.ID, models.OrderPlaced)
+	require.NoError(t, err)
+	if assert.Len(t, placed, 1) {
+		assert.Equal(t, orders[0].ID, placed[0].ID)
+	}
+}
+
+func testSetOrderStatus(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	cup := createMerch(t, store, "test-cup", 20)
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price))
+	orders, err := store.ListOrders(context.Background(), alice.ID, "")
+	require.NoError(t, err)
+	id := orders[0].ID
+
+	_, err = store.SetOrderStatus(context.Background(), id, models.OrderHandedOver)
+	assert.ErrorIs(t, err, models.ErrStatusTransition)
+
+	order, err := store.SetOrderStatus(context.Background(), id, models.OrderPacked)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderPacked, order.Status)
+	assert.Equal(t, "test-cup", order.Item)
+	assert.False(t, order.UpdatedAt.Before(order.CreatedAt))
+
+	order, err = store.SetOrderStatus(context.Background(), id, models.OrderHandedOver)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderHandedOver, order.Status)
+
+	_, err = store.SetOrderStatus(context.Background(), id, models.OrderCancelled)
+	assert.ErrorIs(t, err, models.ErrStatusTransition)
+	_, err = store.SetOrderStatus(context.Background(), id+100, models.OrderPacked)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	orders, err = store.ListOrders(context.Background(), alice.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderHandedOver, orders[0].Status)
+}
+
+func testSetOrderStatusConcurrent(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	cup := createMerch(t, store, "test-cup", 20)
+	require.NoError(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price))
+	orders, err := store.ListOrders(context.Background(), alice.ID, "")
+	require.NoError(t, err)
+
+	// several managers pack the same order at once, only one of them succeeds
+	errs := make([]error, 5)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = store.SetOrderStatus(context.Background(), orders[0].ID, models.OrderPacked)
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, models.ErrStatusTransition)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+// buy buys merch for user and returns id of order placed by purchase...
+func buy(t *testing.T, store db.DB, user *models.User, merch *models.Merch, variantID int, price models.Coins) int {
+	t.Helper()
+
+	require.NoError(t, store.BuyMerch(context.Background(), user.ID, merch.ID, variantID, price))
+	orders, err := store.ListOrders(context.Background(), user.ID, "")
+	require.NoError(t, err)
+	return orders[len(orders)-1].ID
+}
+
+func testSetOrderStatusCancelRefunds(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	cup := createMerch(t, store, "test-cup", 20)
+	id := buy(t, store, alice, cup, 0, cup.Price)
+
+	_, err := store.SetOrderStatus(context.Background(), id, models.OrderPacked)
+	require.NoError(t, err)
+	order, err := store.SetOrderStatus(context.Background(), id, models.OrderCancelled)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderCancelled, order.Status)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+
+	history, err := store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.RefundInfo{{OrderID: id, Item: "test-cup", Amount: 20, Reason: db.ManagerCancelReason}}, history.Refunds)
+}
+
+func testRefundOrder(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	stock := 5
+	shirt := createShirt(t, store, "test-shirt", models.MerchVariant{VariantOptions: size("M"), PriceDelta: 10, Stock: &stock})
+	require.NoError(t, store.UpsertMerch(context.Background(), []models.Merch{{Name: "test-cap", Price: 30, Stock: &stock}}))
+	cap, err := store.GetMerchByName(context.Background(), "test-cap")
+	require.NoError(t, err)
+
+	first := buy(t, store, alice, shirt, shirt.Variants[0].ID, 90)
+	second := buy(t, store, alice, shirt, shirt.Variants[0].ID, 90)
+	third := buy(t, store, alice, cap, 0, cap.Price)
+	assert.Equal(t, models.Coins(790), balance(t, store, "alice"))
+
+	order, err := store.RefundOrder(context.Background(), first, models.RefundRules{}, "wrong size")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderCancelled, order.Status)
+	assert.Equal(t, models.Coins(880), balance(t, store, "alice"))
+	_, err = store.RefundOrder(context.Background(), third, models.RefundRules{}, "changed mind")
+	require.NoError(t, err)
+	assert.Equal(t, models.Coins(910), balance(t, store, "alice"))
+
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryInfo{{Type: "test-shirt", Size: "M", Quantity: 1}}, inventory)
+
+	updated, err := store.GetMerchByName(context.Background(), "test-shirt")
+	require.NoError(t, err)
+	assert.Equal(t, 4, *updated.Variants[0].Stock)
+	assert.Equal(t, 5, stockOf(t, store, "test-cap"))
+
+	history, err := store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.RefundInfo{
+		{OrderID: first, Item: "test-shirt", Size: "M", Amount: 90, Reason: "wrong size"},
+		{OrderID: third, Item: "test-cap", Amount: 30, Reason: "changed mind"},
+	}, history.Refunds)
+
+	_, err = store.RefundOrder(context.Background(), first, models.RefundRules{HandedOver: true}, "again")
+	assert.ErrorIs(t, err, models.ErrStatusTransition)
+	_, err = store.RefundOrder(context.Background(), second, models.RefundRules{}, "")
+	assert.ErrorIs(t, err, db.ErrConstraintViolation)
+	_, err = store.RefundOrder(context.Background(), third+100, models.RefundRules{}, "ghost")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	assert.Equal(t, models.Coins(910), balance(t, store, "alice"))
+
+	orders, err := store.ListOrders(context.Background(), alice.ID, models.OrderPlaced)
+	require.NoError(t, err)
+	if assert.Len(t, orders, 1) {
+		assert.Equal(t, second, orders[0].ID)
+	}
+}
+
+func testRefundOrderRules(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	createUser(t, store, "bob")
+	bob, err := store.GetUserByUsername(context.Background(), "bob")
+	require.NoError(t, err)
+	cup := createMerch(t, store, "test-cup", 20)
+	id := buy(t, store, alice, cup, 0, cup.Price)
+
+	_, err = store.RefundOrder(context.Background(), id, models.RefundRules{UserID: bob.ID}, "not mine")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = store.RefundOrder(context.Background(), id, models.RefundRules{UserID: alice.ID, Window: time.Nanosecond}, "too late")
+	assert.ErrorIs(t, err, models.ErrRefundExpired)
+
+	_, err = store.SetOrderStatus(context.Background(), id, models.OrderPacked)
+	require.NoError(t, err)
+	_, err = store.SetOrderStatus(context.Background(), id, models.OrderHandedOver)
+	require.NoError(t, err)
+	_, err = store.RefundOrder(context.Background(), id, models.RefundRules{UserID: alice.ID, Window: time.Hour}, "in time")
+	assert.ErrorIs(t, err, models.ErrStatusTransition)
+	assert.Equal(t, models.Coins(980), balance(t, store, "alice"))
+
+	order, err := store.RefundOrder(context.Background(), id, models.RefundRules{HandedOver: true}, "returned broken")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderCancelled, order.Status)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+}
+
+func testRefundOrderConcurrent(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	cup := createMerch(t, store, "test-cup", 20)
+	id := buy(t, store, alice, cup, 0, cup.Price)
+
+	errs := make([]error, 5)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = store.RefundOrder(context.Background(), id, models.RefundRules{UserID: alice.ID}, "double click")
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, models.ErrStatusTransition)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+}
+
+func testRefundOrderFreesLimit(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	cup := createLimitedPerUser(t, store, "test-cup", &models.PurchaseLimit{Lifetime: 1})
+	id := buy(t, store, alice, cup, 0, cup.Price)
+	assert.ErrorIs(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price), models.ErrLimitExceeded)
+
+	_, err := store.RefundOrder(context.Background(), id, models.RefundRules{}, "changed mind")
+	require.NoError(t, err)
+	assert.NoError(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price))
+}
+
+func testGiftMerch(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+	shirt := createShirt(t, store, "test-shirt", models.MerchVariant{VariantOptions: size("M"), PriceDelta: 10})
+
+	require.NoError(t, store.GiftMerch(context.Background(), alice.ID, bob.ID, shirt.ID, shirt.Variants[0].ID, 90))
+	assert.Equal(t, models.Coins(910), balance(t, store, "alice"))
+	assert.Equal(t, models.Coins(1000), balance(t, store, "bob"))
+
+	inventory, err := store.GetUserInventory(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, inventory)
+	inventory, err = store.GetUserInventory(context.Background(), bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryInfo{{Type: "test-shirt", Size: "M", Quantity: 1}}, inventory)
+
+	orders, err := store.ListOrders(context.Background(), bob.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "alice", orders[0].Username)
+	assert.Equal(t, "bob", orders[0].Recipient)
+	id := orders[0].ID
+	orders, err = store.ListOrders(context.Background(), alice.ID, "")
+	require.NoError(t, err)
+	assert.Len(t, orders, 1)
+
+	history, err := store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.GiftInfo{{OrderID: id, Username: "bob", Item: "test-shirt", Size: "M", Amount: 90}}, history.GiftsSent)
+	assert.Empty(t, history.GiftsReceived)
+	history, err = store.GetUserTransactions(context.Background(), bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.GiftInfo{{OrderID: id, Username: "alice", Item: "test-shirt", Size: "M"}}, history.GiftsReceived)
+	assert.Empty(t, history.GiftsSent)
+}
+
+func testGiftMerchRules(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+	cup := createLimitedPerUser(t, store, "test-cup", &models.PurchaseLimit{Lifetime: 1})
+
+	assert.ErrorIs(t, store.GiftMerch(context.Background(), alice.ID, alice.ID, cup.ID, 0, cup.Price), db.ErrConstraintViolation)
+	assert.ErrorIs(t, store.GiftMerch(context.Background(), alice.ID, bob.ID+100, cup.ID, 0, cup.Price), db.ErrConstraintViolation)
+	assert.ErrorIs(t, store.GiftMerch(context.Background(), alice.ID, bob.ID, cup.ID, 0, 1001), db.ErrInsufficientFunds)
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+
+	// gifts count toward limit of buyer, not of recipient
+	require.NoError(t, store.GiftMerch(context.Background(), alice.ID, bob.ID, cup.ID, 0, cup.Price))
+	assert.ErrorIs(t, store.BuyMerch(context.Background(), alice.ID, cup.ID, 0, cup.Price), models.ErrLimitExceeded)
+	assert.NoError(t, store.BuyMerch(context.Background(), bob.ID, cup.ID, 0, cup.Price))
+}
+
+func testGiftMerchRefund(t *testing.T, store db.DB) {
+	alice := createUser(t, store, "alice")
+	bob := createUser(t, store, "bob")
+	cup := createMerch(t, store, "test-cup", 20)
+
+	require.NoError(t, store.GiftMerch(context.Background(), alice.ID, bob.ID, cup.ID, 0, cup.Price))
+	orders, err := store.ListOrders(context.Background(), alice.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+
+	_, err = store.RefundOrder(context.Background(), orders[0].ID, models.RefundRules{UserID: bob.ID}, "not my style")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	order, err := store.RefundOrder(context.Background(), orders[0].ID, models.RefundRules{UserID: alice.ID}, "bob has one")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderCancelled, order.Status)
+
+	assert.Equal(t, models.Coins(1000), balance(t, store, "alice"))
+	inventory, err := store.GetUserInventory(context.Background(), bob.ID)
+	require.NoError(t, err)
+	assert.Empty(t, inventory)
+
+	// refunded gift stays in history of both users, marked as refunded
+	history, err := store.GetUserTransactions(context.Background(), bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.GiftInfo{{OrderID: order.ID, Username: "alice", Item: "test-cup", Refunded: true}},
+		history.GiftsReceived)
+	history, err = store.GetUserTransactions(context.Background(), alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.GiftInfo{{OrderID: order.ID, Username: "bob", Item: "test-cup", Amount: cup.Price, Refunded: true}},
+		history.GiftsSent)
+}
+
+func testHealth(t *testing.T, store db.DB) {
+	assert.NoError(t, store.Ping(context.Background()))
+	assert.NoError(t, store.CheckSchema(context.Background()))
+}
@@ -0,0 +1,35 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"merch_store/internal/db"
+	"merch_store/internal/db/pgtest"
+	"merch_store/internal/seed"
+)
+
+// NewPostgres returns database in fresh schema with all migrations applied and default catalog loaded,
+// test package must start PostgreSQL with pgtest.Run and test is skipped when it is not available...
+func NewPostgres(t testing.TB) *db.Database {
+	t.Helper()
+
+	database, err := db.Connect(context.Background(), pgtest.NewSchema(t))
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	known, err := db.EmbeddedMigrations()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err = database.MigrateUp(context.Background(), known); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+
+	if _, err = seed.Load(context.Background(), database, "", false); err != nil {
+		t.Fatalf("load catalog: %v", err)
+	}
+	return database
+}
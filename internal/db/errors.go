@@ -0,0 +1,72 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"merch_store/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Errors returned by DB implementations, callers should check them with errors.Is.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrConstraintViolation = errors.New("constraint violation")
+	ErrConflict            = errors.New("conflict")
+	ErrOutOfStock          = errors.New("out of stock")
+	ErrNotReversible       = errors.New("transfer can't be reversed")
+)
+
+// PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	numericValueOutOfRangeCode = "22003"
+	notNullViolationCode       = "23502"
+	foreignKeyViolationCode    = "23503"
+	uniqueViolationCode        = "23505"
+	checkViolationCode         = "23514"
+)
+
+// Names of CHECK constraints generated by PostgreSQL.
+const (
+	usersCoinsCheck   = "users_coins_check"
+	merchStockCheck   = "merch_stock_check"
+	variantStockCheck = "merch_variants_stock_check"
+)
+
+// translateError wraps err into one of exported sentinel errors, original error stays in chain...
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case numericValueOutOfRangeCode:
+		return fmt.Errorf("%w: %w", models.ErrCoinsOverflow, err)
+	case checkViolationCode:
+		switch pgErr.ConstraintName {
+		case usersCoinsCheck:
+			return fmt.Errorf("%w: %w", ErrInsufficientFunds, err)
+		case merchStockCheck, variantStockCheck:
+			return fmt.Errorf("%w: %w", ErrOutOfStock, err)
+		}
+		return fmt.Errorf("%w: %w", ErrConstraintViolation, err)
+	case notNullViolationCode, foreignKeyViolationCode:
+		return fmt.Errorf("%w: %w", ErrConstraintViolation, err)
+	case uniqueViolationCode, serializationFailureCode, deadlockDetectedCode:
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,734 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"merch_store/internal/models"
+)
+
+type inventoryKey struct {
+	userID    int
+	merchID   int
+	variantID int
+}
+
+// purchase is also the order placed by it, ID of order is position of purchase in MemoryDatabase.purchases plus one,
+// inventoryKey identifies buyer and bought item, recipientID is set for gifts.
+type purchase struct {
+	inventoryKey
+	recipientID int
+	price       models.Coins
+	at          time.Time
+	status      models.OrderStatus
+	updatedAt   time.Time
+	refunded    bool
+}
+
+// owned returns key of inventory item went to...
+func (p *purchase) owned() inventoryKey {
+	key := p.inventoryKey
+	if p.recipientID != 0 {
+		key.userID = p.recipientID
+	}
+	return key
+}
+
+type memoryRefund struct {
+	orderID int
+	reason  string
+}
+
+// MemoryDatabase is thread-safe in-memory implementation of DB, it follows constraints of PostgreSQL schema
+// and is meant for tests that don't need real database...
+type MemoryDatabase struct {
+	mu sync.RWMutex
+
+	users        map[int]*models.User
+	merch        map[int]*models.Merch
+	inventory    map[inventoryKey]int
+	transactions []models.Transaction
+	purchases    []purchase
+	refunds      []memoryRefund
+
+	nextUserID        int
+	nextMerchID       int
+	nextVariantID     int
+	nextTransactionID int
+}
+
+// NewMemoryDatabase creates empty in-memory database...
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{
+		users:     make(map[int]*models.User),
+		merch:     make(map[int]*models.Merch),
+		inventory: make(map[inventoryKey]int),
+	}
+}
+
+// Close function does nothing, data stays in memory until database is garbage collected...
+func (db *MemoryDatabase) Close() error {
+	return nil
+}
+
+// Ping always succeeds unless context is done...
+func (db *MemoryDatabase) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// CheckSchema always succeeds, in-memory database has no migrations...
+func (db *MemoryDatabase) CheckSchema(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// PutUser stores user with given balance, it lets tests prepare state that can't be reached through DB methods...
+func (db *MemoryDatabase) PutUser(username, passwordHash string, coins models.Coins) *models.User {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.nextUserID++
+	user := &models.User{ID: db.nextUserID, Username: username, PasswordHash: passwordHash, Coins: coins, Role: models.RoleEmployee}
+	db.users[user.ID] = user
+
+	stored := *user
+	return &stored
+}
+
+// PutInventory sets quantity of merch owned by user...
+func (db *MemoryDatabase) PutInventory(userID, merchID, quantity int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.inventory[inventoryKey{userID: userID, merchID: merchID}] = quantity
+}
+
+// copyMerch copies merch deeply, so callers can't change stored merch through its pointers.
+func copyMerch(merch *models.Merch) models.Merch {
+	copied := *merch
+	if merch.Stock != nil {
+		stock := *merch.Stock
+		copied.Stock = &stock
+	}
+	if merch.Limit != nil {
+		limit := *merch.Limit
+		copied.Limit = &limit
+	}
+	copied.Variants = nil
+	for _, variant := range merch.Variants {
+		if variant.Stock != nil {
+			stock := *variant.Stock
+			variant.Stock = &stock
+		}
+		copied.Variants = append(copied.Variants, variant)
+	}
+	return copied
+}
+
+func (db *MemoryDatabase) userByName(username string) *models.User {
+	for _, user := range db.users {
+		if user.Username == username {
+			return user
+		}
+	}
+	return nil
+}
+
+func (db *MemoryDatabase) merchByName(name string) *models.Merch {
+	for _, merch := range db.merch {
+		if merch.Name == name {
+			return merch
+		}
+	}
+	return nil
+}
+
+// GetUserByUsername finds user by name...
+func (db *MemoryDatabase) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	user := db.userByName(username)
+	if user == nil {
+		return nil, fmt.Errorf("user %q: %w", username, ErrNotFound)
+	}
+
+	found := *user
+	return &found, nil
+}
+
+// CreateUser creates user with models.DefaultCoins, coins of user are ignored...
+func (db *MemoryDatabase) CreateUser(ctx context.Context, user *models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userByName(user.Username) != nil {
+		return fmt.Errorf("user %q: %w", user.Username, ErrConflict)
+	}
+
+	db.nextUserID++
+	db.users[db.nextUserID] = &models.User{
+		ID:           db.nextUserID,
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		Coins:        models.DefaultCoins,
+		Role:         models.RoleEmployee,
+	}
+	return nil
+}
+
+// TransferCoins sends coins from one user to another, nothing changes if any check fails...
+func (db *MemoryDatabase) TransferCoins(ctx context.Context, fromUserID, toUserID int, amount models.Coins) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// checks go in the same order as statements in Database.TransferCoins, so both implementations fail alike
+	sender, ok := db.users[fromUserID]
+	if !ok {
+		return fmt.Errorf("sender %d: %w", fromUserID, ErrNotFound)
+	}
+	senderCoins, err := sender.Coins.Sub(amount)
+	if err != nil {
+		return err
+	}
+	if senderCoins < 0 {
+		return fmt.Errorf("sender %d: %w", fromUserID, ErrInsufficientFunds)
+	}
+
+	recipient, ok := db.users[toUserID]
+	if !ok {
+		return fmt.Errorf("recipient %d: %w", toUserID, ErrNotFound)
+	}
+	recipientCoins := recipient.Coins
+	if fromUserID == toUserID {
+		recipientCoins = senderCoins
+	}
+	recipientCoins, err = recipientCoins.Add(amount)
+	if err != nil {
+		return err
+	}
+	if recipientCoins < 0 {
+		return fmt.Errorf("recipient %d: %w", toUserID, ErrInsufficientFunds)
+	}
+
+	if amount <= 0 {
+		return fmt.Errorf("amount %d: %w", amount, ErrConstraintViolation)
+	}
+
+	sender.Coins = senderCoins
+	recipient.Coins = recipientCoins
+
+	db.nextTransactionID++
+	db.transactions = append(db.transactions, models.Transaction{
+		ID:         db.nextTransactionID,
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Amount:     amount,
+	})
+	return nil
+}
+
+// ReverseTransfer sends coins of transfer back from its recipient to sender with compensating transfer linked
+// to original one, it returns the compensating transfer. Checks follow Database.ReverseTransfer...
+func (db *MemoryDatabase) ReverseTransfer(ctx context.Context, transactionID int) (*models.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if transactionID <= 0 || transactionID > len(db.transactions) {
+		return nil, fmt.Errorf("transfer %d: %w", transactionID, ErrNotFound)
+	}
+	original := db.transactions[transactionID-1]
+	if original.ReversalOf != 0 {
+		return nil, fmt.Errorf("transfer %d is reversal itself: %w", transactionID, ErrNotReversible)
+	}
+	if db.reversalOf(transactionID) != 0 {
+		return nil, fmt.Errorf("transfer %d is already reversed: %w", transactionID, ErrNotReversible)
+	}
+
+	recipient, sender := db.users[original.ToUserID], db.users[original.FromUserID]
+	recipientCoins, err := recipient.Coins.Sub(original.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if recipientCoins < 0 {
+		return nil, fmt.Errorf("recipient %d: %w", original.ToUserID, ErrInsufficientFunds)
+	}
+	senderCoins := sender.Coins
+	if sender == recipient {
+		senderCoins = recipientCoins
+	}
+	senderCoins, err = senderCoins.Add(original.Amount)
+	if err != nil {
+		return nil, err
+	}
+	recipient.Coins = recipientCoins
+	sender.Coins = senderCoins
+
+	db.nextTransactionID++
+	reversal := models.Transaction{
+		ID:         db.nextTransactionID,
+		FromUserID: original.ToUserID,
+		ToUserID:   original.FromUserID,
+		Amount:     original.Amount,
+		ReversalOf: transactionID,
+	}
+	db.transactions = append(db.transactions, reversal)
+	return &reversal, nil
+}
+
+// reversalOf returns id of transfer that reversed given one, or zero if it isn't reversed...
+func (db *MemoryDatabase) reversalOf(transactionID int) int {
+	for _, transaction := range db.transactions {
+		if transaction.ReversalOf == transactionID {
+			return transaction.ID
+		}
+	}
+	return 0
+}
+
+// SetUserRole changes role of user...
+func (db *MemoryDatabase) SetUserRole(ctx context.Context, username string, role models.Role) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !role.Valid() {
+		return nil, fmt.Errorf("role %q: %w", role, ErrConstraintViolation)
+	}
+	user := db.userByName(username)
+	if user == nil {
+		return nil, fmt.Errorf("user %q: %w", username, ErrNotFound)
+	}
+	user.Role = role
+
+	updated := *user
+	return &updated, nil
+}
+
+// GetMerchByName finds merch by it's name...
+func (db *MemoryDatabase) GetMerchByName(ctx context.Context, name string) (*models.Merch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	merch := db.merchByName(name)
+	if merch == nil {
+		return nil, fmt.Errorf("merch %q: %w", name, ErrNotFound)
+	}
+
+	found := copyMerch(merch)
+	return &found, nil
+}
+
+// UpsertMerch creates merch items and their variants or updates price, limit and price deltas of existing ones,
+// stock is set only for new items and variants, either all items are stored or none...
+func (db *MemoryDatabase) UpsertMerch(ctx context.Context, items []models.Merch) error {
+	return db.storeMerch(ctx, items, true)
+}
+
+// InsertMerch creates merch items and variants that don't exist yet, existing ones are left untouched...
+func (db *MemoryDatabase) InsertMerch(ctx context.Context, items []models.Merch) error {
+	return db.storeMerch(ctx, items, false)
+}
+
+// storeMerch stores items, price, limit and price deltas of existing ones are changed only with overwrite...
+func (db *MemoryDatabase) storeMerch(ctx context.Context, items []models.Merch, overwrite bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := item.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrConstraintViolation, err)
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, item := range items {
+		stored := copyMerch(&item)
+		merch := db.merchByName(item.Name)
+		created := merch == nil
+		if created {
+			db.nextMerchID++
+			merch = &models.Merch{ID: db.nextMerchID, Name: stored.Name, Stock: stored.Stock}
+			db.merch[merch.ID] = merch
+		}
+		if created || overwrite {
+			merch.Price = stored.Price
+			merch.Limit = stored.Limit
+		}
+
+	variants:
+		for _, variant := range stored.Variants {
+			for i := range merch.Variants {
+				if merch.Variants[i].VariantOptions == variant.VariantOptions {
+					if overwrite {
+						merch.Variants[i].PriceDelta = variant.PriceDelta
+					}
+					continue variants
+				}
+			}
+			db.nextVariantID++
+			variant.ID = db.nextVariantID
+			merch.Variants = append(merch.Variants, variant)
+		}
+	}
+	return nil
+}
+
+// ListMerch returns all merch sorted by name...
+func (db *MemoryDatabase) ListMerch(ctx context.Context) ([]models.Merch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var catalog []models.Merch
+	for _, merch := range db.merch {
+		catalog = append(catalog, copyMerch(merch))
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Name < catalog[j].Name })
+
+	return catalog, nil
+}
+
+// BuyMerch charges user, takes item from limited stock of merch and variant and adds it to their inventory
+// if purchase limit allows, variantID is zero for merch without variants...
+func (db *MemoryDatabase) BuyMerch(ctx context.Context, userID, merchID, variantID int, price models.Coins) error {
+	return db.buyMerch(ctx, userID, 0, merchID, variantID, price)
+}
+
+// GiftMerch buys merch like BuyMerch does, but item goes to inventory of recipient,
+// gifts count toward purchase limit of buyer...
+func (db *MemoryDatabase) GiftMerch(ctx context.Context, buyerID, recipientID, merchID, variantID int, price models.Coins) error {
+	return db.buyMerch(ctx, buyerID, recipientID, merchID, variantID, price)
+}
+
+func (db *MemoryDatabase) buyMerch(ctx context.Context, userID, recipientID, merchID, variantID int, price models.Coins) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	user, ok := db.users[userID]
+	if !ok {
+		return fmt.Errorf("user %d: %w", userID, ErrNotFound)
+	}
+	coins, err := user.Coins.Sub(price)
+	if err != nil {
+		return err
+	}
+	if coins < 0 {
+		return fmt.Errorf("user %d: %w", userID, ErrInsufficientFunds)
+	}
+
+	merch, ok := db.merch[merchID]
+	if !ok {
+		return fmt.Errorf("merch %d: %w", merchID, ErrConstraintViolation)
+	}
+	if !merch.InStock() {
+		return fmt.Errorf("merch %d: %w", merchID, ErrOutOfStock)
+	}
+
+	var variant *models.MerchVariant
+	if variantID != 0 {
+		for i := range merch.Variants {
+			if merch.Variants[i].ID == variantID {
+				variant = &merch.Variants[i]
+			}
+		}
+		if variant == nil {
+			return fmt.Errorf("merch %d variant %d: %w", merchID, variantID, ErrConstraintViolation)
+		}
+		if !variant.InStock() {
+			return fmt.Errorf("merch %d variant %d: %w", merchID, variantID, ErrOutOfStock)
+		}
+	} else if len(merch.Variants) > 0 {
+		return fmt.Errorf("merch %d is sold only as variants: %w", merchID, ErrConstraintViolation)
+	}
+
+	bought := purchase{
+		inventoryKey: inventoryKey{userID: userID, merchID: merchID, variantID: variantID},
+		recipientID:  recipientID,
+		price:        price,
+		status:       models.OrderPlaced,
+	}
+	now := time.Now()
+	total, inWindow := 0, 0
+	for _, p := range db.purchases {
+		// limit counts all variants of merch
+		if p.userID != userID || p.merchID != merchID || p.refunded {
+			continue
+		}
+		total++
+		if merch.Limit != nil && now.Sub(p.at) < merch.Limit.Window() {
+			inWindow++
+		}
+	}
+	if err = merch.Limit.Check(total, inWindow); err != nil {
+		return fmt.Errorf("merch %d: %w", merchID, err)
+	}
+	// PostgreSQL checks recipient with foreign key and CHECK constraint after the limit is counted
+	if recipientID != 0 {
+		if _, ok = db.users[recipientID]; !ok || recipientID == userID {
+			return fmt.Errorf("recipient %d: %w", recipientID, ErrConstraintViolation)
+		}
+	}
+
+	user.Coins = coins
+	if merch.Stock != nil {
+		*merch.Stock--
+	}
+	if variant != nil && variant.Stock != nil {
+		*variant.Stock--
+	}
+	bought.at, bought.updatedAt = now, now
+	db.inventory[bought.owned()]++
+	db.purchases = append(db.purchases, bought)
+	return nil
+}
+
+// GetUserInventory gets user inventory sorted by merch name, variants follow in order they were added...
+func (db *MemoryDatabase) GetUserInventory(ctx context.Context, userID int) ([]models.InventoryInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var keys []inventoryKey
+	for key := range db.inventory {
+		if key.userID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].merchID != keys[j].merchID {
+			return db.merch[keys[i].merchID].Name < db.merch[keys[j].merchID].Name
+		}
+		return keys[i].variantID < keys[j].variantID
+	})
+
+	var inventory []models.InventoryInfo
+	for _, key := range keys {
+		merch := db.merch[key.merchID]
+		item := models.InventoryInfo{Type: merch.Name, Quantity: db.inventory[key]}
+		for _, variant := range merch.Variants {
+			if variant.ID == key.variantID {
+				item.Size, item.Color = variant.Size, variant.Color
+			}
+		}
+		inventory = append(inventory, item)
+	}
+
+	return inventory, nil
+}
+
+// GetUserTransactions gets coins received and sent by user and refunds in order they were made...
+func (db *MemoryDatabase) GetUserTransactions(ctx context.Context, userID int) (models.CoinHistory, error) {
+	var history models.CoinHistory
+	if err := ctx.Err(); err != nil {
+		return history, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, transaction := range db.transactions {
+		info := models.TransactionInfo{
+			ID:         transaction.ID,
+			Amount:     transaction.Amount,
+			ReversalOf: transaction.ReversalOf,
+			ReversedBy: db.reversalOf(transaction.ID),
+		}
+		if transaction.ToUserID == userID {
+			info.Username = db.users[transaction.FromUserID].Username
+			history.Received = append(history.Received, info)
+		}
+		if transaction.FromUserID == userID {
+			info.Username = db.users[transaction.ToUserID].Username
+			history.Sent = append(history.Sent, info)
+		}
+	}
+
+	for _, r := range db.refunds {
+		p := db.purchases[r.orderID-1]
+		if p.userID != userID {
+			continue
+		}
+		order := db.order(r.orderID)
+		history.Refunds = append(history.Refunds, models.RefundInfo{
+			OrderID: order.ID,
+			Item:    order.Item,
+			Size:    order.Size,
+			Color:   order.Color,
+			Amount:  p.price,
+			Reason:  r.reason,
+		})
+	}
+
+	for i, p := range db.purchases {
+		if p.recipientID == 0 || (p.userID != userID && p.recipientID != userID) {
+			continue
+		}
+		order := db.order(i + 1)
+		gift := models.GiftInfo{OrderID: order.ID, Item: order.Item, Size: order.Size, Color: order.Color, Refunded: p.refunded}
+		if p.userID == userID {
+			gift.Username, gift.Amount = order.Recipient, p.price
+			history.GiftsSent = append(history.GiftsSent, gift)
+		} else {
+			gift.Username = order.Username
+			history.GiftsReceived = append(history.GiftsReceived, gift)
+		}
+	}
+
+	return history, nil
+}
+
+func (db *MemoryDatabase) order(id int) models.Order {
+	p := db.purchases[id-1]
+	merch := db.merch[p.merchID]
+	order := models.Order{
+		ID:        id,
+		Username:  db.users[p.userID].Username,
+		Item:      merch.Name,
+		Status:    p.status,
+		CreatedAt: p.at,
+		UpdatedAt: p.updatedAt,
+	}
+	if p.recipientID != 0 {
+		order.Recipient = db.users[p.recipientID].Username
+	}
+	for _, variant := range merch.Variants {
+		if variant.ID == p.variantID {
+			order.Size, order.Color = variant.Size, variant.Color
+		}
+	}
+	return order
+}
+
+// ListOrders returns orders sorted by time they were placed, zero userID means orders of all users
+// and empty status means orders in any status...
+func (db *MemoryDatabase) ListOrders(ctx context.Context, userID int, status models.OrderStatus) ([]models.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var orders []models.Order
+	for i, p := range db.purchases {
+		if (userID == 0 || p.userID == userID || p.recipientID == userID) && (status == "" || p.status == status) {
+			orders = append(orders, db.order(i+1))
+		}
+	}
+	return orders, nil
+}
+
+// SetOrderStatus moves order to status, it returns models.ErrStatusTransition if order can't move there
+// from its current status, cancelled order is refunded...
+func (db *MemoryDatabase) SetOrderStatus(ctx context.Context, orderID int, status models.OrderStatus) (*models.Order, error) {
+	return db.updateOrder(ctx, orderID, status, ManagerCancelReason, func(p *purchase) error {
+		return p.status.CheckTransition(status)
+	})
+}
+
+// RefundOrder cancels order allowed by rules, returns its price to user, takes item from inventory of user
+// and puts it back to stock, refund is recorded with reason...
+func (db *MemoryDatabase) RefundOrder(ctx context.Context, orderID int, rules models.RefundRules, reason string) (*models.Order, error) {
+	return db.updateOrder(ctx, orderID, models.OrderCancelled, reason, func(p *purchase) error {
+		if rules.UserID != 0 && p.userID != rules.UserID {
+			return ErrNotFound
+		}
+		return rules.CheckRefund(p.status, time.Since(p.at))
+	})
+}
+
+// updateOrder checks order and moves it to status, order moved to cancelled status is refunded with reason,
+// nothing changes if any check fails...
+func (db *MemoryDatabase) updateOrder(ctx context.Context, orderID int, status models.OrderStatus, reason string,
+	check func(p *purchase) error) (*models.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if orderID <= 0 || orderID > len(db.purchases) {
+		return nil, fmt.Errorf("order %d: %w", orderID, ErrNotFound)
+	}
+	p := &db.purchases[orderID-1]
+	if err := check(p); err != nil {
+		return nil, fmt.Errorf("order %d: %w", orderID, err)
+	}
+
+	if status == models.OrderCancelled {
+		if reason == "" {
+			return nil, fmt.Errorf("order %d: empty refund reason: %w", orderID, ErrConstraintViolation)
+		}
+		user := db.users[p.userID]
+		coins, err := user.Coins.Add(p.price)
+		if err != nil {
+			return nil, err
+		}
+		owned := p.owned()
+		if db.inventory[owned] == 0 {
+			return nil, fmt.Errorf("order %d: item is not in inventory: %w", orderID, ErrConstraintViolation)
+		}
+
+		user.Coins = coins
+		if db.inventory[owned]--; db.inventory[owned] == 0 {
+			delete(db.inventory, owned)
+		}
+		merch := db.merch[p.merchID]
+		if merch.Stock != nil {
+			*merch.Stock++
+		}
+		for i := range merch.Variants {
+			if merch.Variants[i].ID == p.variantID && merch.Variants[i].Stock != nil {
+				*merch.Variants[i].Stock++
+			}
+		}
+		p.refunded = true
+		db.refunds = append(db.refunds, memoryRefund{orderID: orderID, reason: reason})
+	}
+
+	p.status = status
+	p.updatedAt = time.Now()
+
+	order := db.order(orderID)
+	return &order, nil
+}
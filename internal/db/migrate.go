@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"merch_store/internal/logging"
+	"merch_store/migrations"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// migrationLockID is key of advisory lock taken while migration is applied, so concurrent runners wait for each other.
+const migrationLockID = 7_315_001
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change...
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether migration has been applied...
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads migrations from fsys and sorts them by version...
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	loaded := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		loaded = append(loaded, *migration)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Version < loaded[j].Version })
+
+	return loaded, nil
+}
+
+// EmbeddedMigrations returns migrations shipped with the server...
+func EmbeddedMigrations() ([]Migration, error) {
+	return LoadMigrations(migrations.FS)
+}
+
+func (db *Database) ensureMigrationsTable(ctx context.Context) error {
+	_, err := db.Pool.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	return err
+}
+
+// MigrateUp applies all pending migrations and returns versions that were applied...
+func (db *Database) MigrateUp(ctx context.Context, pending []Migration) ([]int, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, migration := range pending {
+		done := false
+		err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+				return err
+			}
+
+			var exists bool
+			err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", migration.Version).
+				Scan(&exists)
+			if err != nil || exists {
+				return err
+			}
+
+			if _, err = tx.Exec(ctx, migration.Up); err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+			done = err == nil
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		if done {
+			logging.FromContext(ctx).Info("applied migration", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+			applied = append(applied, migration.Version)
+		}
+	}
+
+	return applied, nil
+}
+
+// MigrateDown reverts up to steps latest applied migrations and returns versions that were reverted...
+func (db *Database) MigrateDown(ctx context.Context, known []Migration, steps int) ([]int, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]Migration, len(known))
+	for _, migration := range known {
+		byVersion[migration.Version] = migration
+	}
+
+	var reverted []int
+	for i := 0; i < steps; i++ {
+		version := 0
+		err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+				return err
+			}
+
+			err := tx.QueryRow(ctx, "SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1").Scan(&version)
+			if errors.Is(err, pgx.ErrNoRows) {
+				version = 0
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			migration, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("applied migration %d is unknown", version)
+			}
+
+			if _, err = tx.Exec(ctx, migration.Down); err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", version)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("revert migration %d: %w", version, err)
+		}
+		if version == 0 {
+			break
+		}
+
+		logging.FromContext(ctx).Info("reverted migration", slog.Int("version", version))
+		reverted = append(reverted, version)
+	}
+
+	return reverted, nil
+}
+
+// MigrationsStatus returns status of every known migration...
+func (db *Database) MigrationsStatus(ctx context.Context, known []Migration) ([]MigrationStatus, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Pool.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(known))
+	for _, migration := range known {
+		status := MigrationStatus{Migration: migration}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
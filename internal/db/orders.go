@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"merch_store/internal/models"
+	"merch_store/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ordersQuery selects orders with names of user, merch and variant, condition is appended to it.
+const ordersQuery = `
+    SELECT p.id, u.username, COALESCE(g.username, ''), m.name, COALESCE(v.size, ''), COALESCE(v.color, ''),
+        o.status, p.created_at, o.updated_at
+    FROM orders o
+    JOIN purchases p ON p.id = o.purchase_id
+    JOIN users u ON u.id = p.user_id
+    LEFT JOIN users g ON g.id = p.recipient_id
+    JOIN merch m ON m.id = p.merch_id
+    LEFT JOIN merch_variants v ON v.id = p.variant_id
+`
+
+func queryOrders(ctx context.Context, q querier, where string, args ...any) ([]models.Order, error) {
+	rows, err := q.Query(ctx, ordersQuery+"WHERE "+where+" ORDER BY p.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		var order models.Order
+		err = rows.Scan(&order.ID, &order.Username, &order.Recipient, &order.Item, &order.Size, &order.Color,
+			&order.Status, &order.CreatedAt, &order.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// ListOrders returns orders sorted by time they were placed, orders of user include gifts bought for that user,
+// zero userID means orders of all users and empty status means orders in any status...
+func (db *Database) ListOrders(ctx context.Context, userID int, status models.OrderStatus) ([]models.Order, error) {
+	ctx, span := tracing.Start(ctx, "db.ListOrders")
+	defer span.End()
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	return queryOrders(ctx, db.Pool, "($1 = 0 OR p.user_id = $1 OR p.recipient_id = $1) AND ($2::TEXT = '' OR o.status = $2::TEXT)", userID, status)
+}
+
+// Reasons recorded for refunds of orders cancelled through order workflow.
+const (
+	ManagerCancelReason = "cancelled by store manager"
+	UserCancelReason    = "cancelled by user"
+)
+
+// lockedOrder is purchase of order locked for update, ownerID is user who got item, it differs from userID for gifts...
+type lockedOrder struct {
+	userID    int
+	ownerID   int
+	merchID   int
+	variantID int
+	price     models.Coins
+	status    models.OrderStatus
+	age       time.Duration
+}
+
+// SetOrderStatus moves order to status, it returns models.ErrStatusTransition if order can't move there
+// from its current status, cancelled order is refunded...
+func (db *Database) SetOrderStatus(ctx context.Context, orderID int, status models.OrderStatus) (*models.Order, error) {
+	ctx, span := tracing.Start(ctx, "db.SetOrderStatus")
+	defer span.End()
+
+	return db.updateOrder(ctx, orderID, status, ManagerCancelReason, func(order *lockedOrder) error {
+		return order.status.CheckTransition(status)
+	})
+}
+
+// RefundOrder cancels order allowed by rules, returns its price to user, takes item from inventory of user
+// and puts it back to stock, refund is recorded with reason...
+func (db *Database) RefundOrder(ctx context.Context, orderID int, rules models.RefundRules, reason string) (*models.Order, error) {
+	ctx, span := tracing.Start(ctx, "db.RefundOrder")
+	defer span.End()
+
+	return db.updateOrder(ctx, orderID, models.OrderCancelled, reason, func(order *lockedOrder) error {
+		if rules.UserID != 0 && order.userID != rules.UserID {
+			// other users' orders are not revealed
+			return ErrNotFound
+		}
+		return rules.CheckRefund(order.status, order.age)
+	})
+}
+
+// updateOrder locks order, checks it and moves it to status, order moved to cancelled status is refunded with reason...
+func (db *Database) updateOrder(ctx context.Context, orderID int, status models.OrderStatus, reason string,
+	check func(order *lockedOrder) error) (*models.Order, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var updated models.Order
+	err := db.withTx(ctx, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		// lock makes concurrent managers and users see status set by the first of them
+		var order lockedOrder
+		var seconds float64
+		err := tx.QueryRow(ctx, `
+            SELECT p.user_id, COALESCE(p.recipient_id, p.user_id), p.merch_id, COALESCE(p.variant_id, 0), p.price, o.status,
+                EXTRACT(EPOCH FROM LOCALTIMESTAMP - p.created_at)::FLOAT8
+            FROM orders o
+            JOIN purchases p ON p.id = o.purchase_id
+            WHERE o.purchase_id = $1
+            FOR UPDATE OF o
+        `, orderID).Scan(&order.userID, &order.ownerID, &order.merchID, &order.variantID, &order.price, &order.status, &seconds)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("order %d: %w", orderID, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		order.age = time.Duration(seconds * float64(time.Second))
+
+		if err = check(&order); err != nil {
+			return fmt.Errorf("order %d: %w", orderID, err)
+		}
+		if status == models.OrderCancelled {
+			if err = refund(ctx, tx, orderID, &order, reason); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE purchase_id = $2", status, orderID)
+		if err != nil {
+			return err
+		}
+
+		orders, err := queryOrders(ctx, tx, "o.purchase_id = $1", orderID)
+		if err != nil {
+			return err
+		}
+		updated = orders[0]
+		return nil
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &updated, nil
+}
+
+// refund undoes purchase of locked order, it is BuyMerch in reverse, coins go back to buyer of gift
+// and item is taken from its recipient...
+func refund(ctx context.Context, tx pgx.Tx, orderID int, order *lockedOrder, reason string) error {
+	_, err := tx.Exec(ctx, "UPDATE users SET coins = coins + $1 WHERE id = $2", order.price, order.userID)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+        UPDATE inventory SET quantity = quantity - 1
+        WHERE user_id = $1 AND merch_id = $2 AND COALESCE(variant_id, 0) = $3 AND quantity > 1
+    `, order.ownerID, order.merchID, order.variantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		tag, err = tx.Exec(ctx, "DELETE FROM inventory WHERE user_id = $1 AND merch_id = $2 AND COALESCE(variant_id, 0) = $3",
+			order.ownerID, order.merchID, order.variantID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("order %d: item is not in inventory: %w", orderID, ErrConstraintViolation)
+		}
+	}
+
+	if _, err = tx.Exec(ctx, "UPDATE merch SET stock = stock + 1 WHERE id = $1 AND stock IS NOT NULL", order.merchID); err != nil {
+		return err
+	}
+	if order.variantID != 0 {
+		_, err = tx.Exec(ctx, "UPDATE merch_variants SET stock = stock + 1 WHERE id = $1 AND stock IS NOT NULL", order.variantID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO refunds (purchase_id, amount, reason) VALUES ($1, $2, $3)", orderID, order.price, reason)
+	return err
+}
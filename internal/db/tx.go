@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"merch_store/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	maxTxAttempts  = 3
+	txRetryBackoff = 20 * time.Millisecond
+)
+
+// Codes of errors after which transaction can be safely retried from the beginning.
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+// withTx runs fn inside transaction and commits it, transaction is rolled back if fn or commit fails.
+// Serialization failures and deadlocks are retried up to maxTxAttempts times...
+func (db *Database) withTx(ctx context.Context, opts pgx.TxOptions, fn func(tx pgx.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = db.runTx(ctx, opts, fn)
+		if err == nil || !isRetryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		logging.FromContext(ctx).Warn("retrying transaction",
+			slog.Int("attempt", attempt),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(time.Duration(attempt) * txRetryBackoff):
+		}
+	}
+	return err
+}
+
+func (db *Database) runTx(ctx context.Context, opts pgx.TxOptions, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := db.Pool.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+
+		if err == nil {
+			return
+		}
+		// Rollback must be attempted even if ctx is already canceled, otherwise connection is left in broken state.
+		if rb := tx.Rollback(context.WithoutCancel(ctx)); rb != nil && !errors.Is(rb, pgx.ErrTxClosed) {
+			logging.FromContext(ctx).Error("failed to roll back transaction", slog.Any("error", rb))
+			err = errors.Join(err, fmt.Errorf("rollback: %w", rb))
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode
+}
@@ -3,44 +3,37 @@ package handlers
 import (
 	"net/http"
 
+	"merch_store/internal/models"
+
 	"github.com/gorilla/mux"
 )
 
-// BuyHandler handles /api/buy/{item}...
+// BuyHandler handles /api/buy/{item}, size and color query parameters select variant,
+// to query parameter makes purchase a gift to another user...
 func (h *Handler) BuyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	claims, err := h.TokenValidator.ValidateToken(r.Header.Get("Authorization"))
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	vars := mux.Vars(r)
 	item, ok := vars["item"]
 	if !ok {
-		http.Error(w, "Item not found in URL; Item is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Item not found in URL; Item is required")
 		return
 	}
 
-	user, err := h.DB.GetUserByUsername(claims.Username)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
+	query := r.URL.Query()
+	options := models.VariantOptions{Size: query.Get("size"), Color: query.Get("color")}
+	if query.Has("to") {
+		err = h.Store.Gift(ctx, claims.Username, query.Get("to"), item, options)
+	} else {
+		err = h.Store.Buy(ctx, claims.Username, item, options)
 	}
-
-	merch, err := h.DB.GetMerchByName(item)
-	if err != nil {
-		http.Error(w, "Item not found", http.StatusNotFound)
-		return
-	}
-
-	if user.Coins < merch.Price {
-		http.Error(w, "Insufficient coins", http.StatusBadRequest)
-		return
-	}
-
-	err = h.DB.BuyMerch(user.ID, merch.ID, merch.Price)
 	if err != nil {
-		http.Error(w, "Failed to buy item", http.StatusInternalServerError)
+		respondError(ctx, w, err)
 		return
 	}
 
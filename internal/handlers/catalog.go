@@ -0,0 +1,20 @@
+package handlers
+
+import "net/http"
+
+// CatalogHandler handles /api/merch...
+func (h *Handler) CatalogHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if _, err := h.TokenValidator.ValidateToken(r.Header.Get("Authorization")); err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	response, err := h.Store.Catalog(ctx)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	writeJSON(ctx, w, http.StatusOK, response)
+}
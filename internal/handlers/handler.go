@@ -1,17 +1,60 @@
 package handlers
 
 import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+
 	"merch_store/internal/auth"
 	"merch_store/internal/db"
+	"merch_store/internal/service"
 )
 
 // Handler - abstract for all handlers...
 type Handler struct {
 	DB             db.DB
 	TokenValidator auth.TokenValidator
+	Accounts       *service.AccountService
+	Wallet         *service.WalletService
+	Store          *service.StoreService
+	Orders         *service.OrderService
+
+	shuttingDown atomic.Bool
 }
 
 // NewHandler generates Handler...
 func NewHandler(db db.DB) *Handler {
-	return &Handler{DB: db, TokenValidator: &auth.DefaultValidator{}}
+	return &Handler{
+		DB:             db,
+		TokenValidator: &auth.DefaultValidator{},
+		Accounts:       service.NewAccountService(db),
+		Wallet:         service.NewWalletService(db),
+		Store:          service.NewStoreService(db),
+		Orders:         service.NewOrderService(db),
+	}
+}
+
+// RegisterRoutes registers health checks and API routes handled by h...
+func (h *Handler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/healthz", h.HealthzHandler)
+	r.HandleFunc("/readyz", h.ReadyzHandler)
+
+	r.HandleFunc("/api/auth", h.AuthHandler)
+	r.HandleFunc("/api/info", h.InfoHandler)
+	r.HandleFunc("/api/sendCoin", h.SendCoinHandler)
+	r.HandleFunc("/api/merch", h.CatalogHandler)
+	r.HandleFunc("/api/buy/{item}", h.BuyHandler)
+	r.HandleFunc("/api/orders", h.OrdersHandler).Methods(http.MethodGet)
+	r.HandleFunc("/api/orders/{id}/status", h.OrderStatusHandler).Methods(http.MethodPost)
+	r.HandleFunc("/api/orders/{id}/cancel", h.CancelOrderHandler).Methods(http.MethodPost)
+}
+
+// Username returns name of user whose token is in request or empty string when token is invalid...
+func (h *Handler) Username(r *http.Request) string {
+	claims, err := h.TokenValidator.ValidateToken(r.Header.Get("Authorization"))
+	if err != nil {
+		return ""
+	}
+	return claims.Username
 }
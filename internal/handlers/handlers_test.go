@@ -2,34 +2,44 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
+	"time"
 
 	"merch_store/internal/auth"
 	"merch_store/internal/db"
 	"merch_store/internal/models"
+	"merch_store/internal/seed"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var testDB *db.Database
+var testDB *db.MemoryDatabase
 var handler *Handler
 var router *mux.Router
+var ctx = context.Background()
+
+// resetDB replaces test database with fresh in-memory one filled with default catalog...
+func resetDB() {
+	testDB = db.NewMemoryDatabase()
+	if _, err := seed.Load(ctx, testDB, "", false); err != nil {
+		log.Fatalf("Failed to load catalog: %v", err)
+	}
+	setupHandler()
+}
 
 func setupHandler() {
 	handler = NewHandler(testDB)
 	router = mux.NewRouter()
-
-	router.HandleFunc("/api/auth", handler.AuthHandler)
-	router.HandleFunc("/api/info", handler.InfoHandler)
-	router.HandleFunc("/api/sendCoin", handler.SendCoinHandler)
-	router.HandleFunc("/api/buy/{item}", handler.BuyHandler)
+	handler.RegisterRoutes(router)
 }
 
 func generateAuthToken(username string) string {
@@ -40,18 +50,8 @@ func generateAuthToken(username string) string {
 	return token
 }
 
-func TestMain(m *testing.M) {
-	db.SetupTestDB(&testDB)
-	setupHandler()
-	code := m.Run()
-
-	db.ClearDatabase(testDB)
-	_ = testDB.Close()
-	os.Exit(code)
-}
-
 func TestAuthHandler_CreateUser(t *testing.T) {
-	db.ClearDatabase(testDB)
+	resetDB()
 
 	reqBody := models.AuthRequest{Username: "newuser", Password: "password"}
 	reqBytes, _ := json.Marshal(reqBody)
@@ -62,13 +62,13 @@ func TestAuthHandler_CreateUser(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	user, err := testDB.GetUserByUsername(reqBody.Username)
+	user, err := testDB.GetUserByUsername(ctx, reqBody.Username)
 	assert.NoError(t, err)
 	assert.Equal(t, reqBody.Username, user.Username)
 }
 
 func TestAuthHandler_Login(t *testing.T) {
-	db.ClearDatabase(testDB)
+	resetDB()
 
 	reqBody := models.AuthRequest{Username: "existinguser", Password: "password"}
 	reqBytes, _ := json.Marshal(reqBody)
@@ -80,30 +80,23 @@ func TestAuthHandler_Login(t *testing.T) {
 		t.Fatalf("Failed to hash password: %v", err)
 	}
 
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ($1, $2, 1000)", reqBody.Username, hashedPassword)
-	if err != nil {
-		t.Fatalf("Failed to insert user: %v", err)
-	}
+	testDB.PutUser(reqBody.Username, string(hashedPassword), 1000)
 
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestInfoHandler(t *testing.T) {
-	db.ClearDatabase(testDB)
+	resetDB()
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
 
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('testuser', $1, 200)", hashedPassword)
+	user := testDB.PutUser("testuser", string(hashedPassword), 200)
+	err := testDB.UpsertMerch(ctx, []models.Merch{{Name: "testitem_for_infohandler", Price: 50}})
 	assert.NoError(t, err)
 
-	user, _ := testDB.GetUserByUsername("testuser")
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO merch (name, price) VALUES ('testitem_for_infohandler', 50)")
-	assert.NoError(t, err)
-
-	merch, _ := testDB.GetMerchByName("testitem_for_infohandler")
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO inventory (user_id, merch_id, quantity) VALUES ($1, $2, 5)", user.ID, merch.ID)
-	assert.NoError(t, err)
+	merch, _ := testDB.GetMerchByName(ctx, "testitem_for_infohandler")
+	testDB.PutInventory(user.ID, merch.ID, 5)
 
 	token := generateAuthToken("testuser") // Generate token
 	req, _ := http.NewRequest("GET", "/api/info", nil)
@@ -117,21 +110,19 @@ func TestInfoHandler(t *testing.T) {
 	err = json.Unmarshal(w.Body.Bytes(), &infoResponse)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 200, infoResponse.Coins)
+	assert.Equal(t, models.Coins(200), infoResponse.Coins)
 	assert.Len(t, infoResponse.Inventory, 1)
 	assert.Equal(t, "testitem_for_infohandler", infoResponse.Inventory[0].Type)
 	assert.Equal(t, 5, infoResponse.Inventory[0].Quantity)
 }
 
 func TestSendCoinHandler(t *testing.T) {
-	db.ClearDatabase(testDB)
+	resetDB()
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
 
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('sender', $1, 100)", hashedPassword)
-	assert.NoError(t, err)
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('receiver', $1, 50)", hashedPassword)
-	assert.NoError(t, err)
+	testDB.PutUser("sender", string(hashedPassword), 100)
+	testDB.PutUser("receiver", string(hashedPassword), 50)
 
 	reqBody := models.SendCoinRequest{ToUser: "receiver", Amount: 20}
 	reqBytes, _ := json.Marshal(reqBody)
@@ -144,21 +135,20 @@ func TestSendCoinHandler(t *testing.T) {
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	updatedSender, _ := testDB.GetUserByUsername("sender")
-	updatedReceiver, _ := testDB.GetUserByUsername("receiver")
+	updatedSender, _ := testDB.GetUserByUsername(ctx, "sender")
+	updatedReceiver, _ := testDB.GetUserByUsername(ctx, "receiver")
 
-	assert.Equal(t, 80, updatedSender.Coins)
-	assert.Equal(t, 70, updatedReceiver.Coins)
+	assert.Equal(t, models.Coins(80), updatedSender.Coins)
+	assert.Equal(t, models.Coins(70), updatedReceiver.Coins)
 }
 
 func TestBuyHandler(t *testing.T) {
-	db.ClearDatabase(testDB)
+	resetDB()
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
 
-	_, err := testDB.Pool.Exec(testDB.Ctx, "INSERT INTO users (username, password_hash, coins) VALUES ('buyer', $1, 200)", hashedPassword)
-	assert.NoError(t, err)
-	_, err = testDB.Pool.Exec(testDB.Ctx, "INSERT INTO merch (name, price) VALUES ('testitem_for_buyhandler', 50)")
+	testDB.PutUser("buyer", string(hashedPassword), 200)
+	err := testDB.UpsertMerch(ctx, []models.Merch{{Name: "testitem_for_buyhandler", Price: 50}})
 	assert.NoError(t, err)
 
 	req, _ := http.NewRequest("POST", "/api/buy/testitem_for_buyhandler", nil)
@@ -170,11 +160,451 @@ func TestBuyHandler(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	updatedBuyer, _ := testDB.GetUserByUsername("buyer")
-	assert.Equal(t, 150, updatedBuyer.Coins)
+	updatedBuyer, _ := testDB.GetUserByUsername(ctx, "buyer")
+	assert.Equal(t, models.Coins(150), updatedBuyer.Coins)
 
-	inventory, _ := testDB.GetUserInventory(updatedBuyer.ID)
+	inventory, _ := testDB.GetUserInventory(ctx, updatedBuyer.ID)
 	assert.Len(t, inventory, 1)
 	assert.Equal(t, "testitem_for_buyhandler", inventory[0].Type)
 	assert.Equal(t, 1, inventory[0].Quantity)
 }
+
+func TestBuyHandler_OutOfStock(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("buyer", "hash", 1000)
+	stock := 1
+	err := testDB.UpsertMerch(ctx, []models.Merch{{Name: "limited", Price: 50, Stock: &stock}})
+	assert.NoError(t, err)
+
+	buy := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/buy/limited", nil)
+		req.Header.Set("Authorization", generateAuthToken("buyer"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, buy().Code)
+
+	w := buy()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Item is out of stock")
+
+	buyer, _ := testDB.GetUserByUsername(ctx, "buyer")
+	assert.Equal(t, models.Coins(950), buyer.Coins)
+
+	req, _ := http.NewRequest("GET", "/api/merch", nil)
+	req.Header.Set("Authorization", generateAuthToken("buyer"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var catalog models.CatalogResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &catalog))
+	soldOut := 0
+	assert.Contains(t, catalog.Items, models.CatalogItem{Name: "limited", Price: 50, Stock: &soldOut})
+	assert.Contains(t, catalog.Items, models.CatalogItem{Name: "cup", Price: 20})
+}
+
+func TestBuyHandler_LimitExceeded(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("buyer", "hash", 1000)
+
+	buy := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/buy/pink-hoody", nil)
+		req.Header.Set("Authorization", generateAuthToken("buyer"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, buy().Code)
+
+	w := buy()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Purchase limit exceeded")
+	assert.Equal(t, models.CodeLimitExceeded, decodeErrors(t, w).Code)
+
+	buyer, _ := testDB.GetUserByUsername(ctx, "buyer")
+	assert.Equal(t, models.Coins(500), buyer.Coins)
+}
+
+func TestBuyHandler_Variant(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("buyer", "hash", 1000)
+
+	buy := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, nil)
+		req.Header.Set("Authorization", generateAuthToken("buyer"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := buy("/api/buy/t-shirt?size=XXS")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Variant not found")
+	assert.Equal(t, http.StatusNotFound, buy("/api/buy/cup?size=M").Code)
+
+	assert.Equal(t, http.StatusOK, buy("/api/buy/t-shirt?size=XL").Code)
+	assert.Equal(t, http.StatusOK, buy("/api/buy/hoody?size=L&color=grey").Code)
+	assert.Equal(t, http.StatusOK, buy("/api/buy/t-shirt").Code)
+
+	buyer, _ := testDB.GetUserByUsername(ctx, "buyer")
+	assert.Equal(t, models.Coins(1000-90-300-80), buyer.Coins)
+
+	inventory, _ := testDB.GetUserInventory(ctx, buyer.ID)
+	assert.Equal(t, []models.InventoryInfo{
+		{Type: "hoody", Size: "L", Color: "grey", Quantity: 1},
+		{Type: "t-shirt", Size: "S", Quantity: 1},
+		{Type: "t-shirt", Size: "XL", Quantity: 1},
+	}, inventory)
+}
+
+func TestBuyHandler_Gift(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("buyer", "hash", 1000)
+	testDB.PutUser("colleague", "hash", 0)
+
+	request := func(method, path, username string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", generateAuthToken(username))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("POST", "/api/buy/t-shirt?size=M&to=colleague", "buyer").Code)
+	assert.Equal(t, http.StatusNotFound, request("POST", "/api/buy/cup?to=nobody", "buyer").Code)
+	w := request("POST", "/api/buy/cup?to=buyer", "buyer")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "cannot gift merch to yourself")
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/buy/cup?to=", "buyer").Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/buy/cup?to=buyer", "colleague").Code)
+
+	var buyer, colleague models.InfoResponse
+	assert.NoError(t, json.Unmarshal(request("GET", "/api/info", "buyer").Body.Bytes(), &buyer))
+	assert.NoError(t, json.Unmarshal(request("GET", "/api/info", "colleague").Body.Bytes(), &colleague))
+
+	assert.Equal(t, models.Coins(920), buyer.Coins)
+	assert.Empty(t, buyer.Inventory)
+	assert.Equal(t, []models.GiftInfo{{OrderID: 1, Username: "colleague", Item: "t-shirt", Size: "M", Amount: 80}}, buyer.CoinHistory.GiftsSent)
+	assert.Equal(t, []models.InventoryInfo{{Type: "t-shirt", Size: "M", Quantity: 1}}, colleague.Inventory)
+	assert.Equal(t, []models.GiftInfo{{OrderID: 1, Username: "buyer", Item: "t-shirt", Size: "M"}}, colleague.CoinHistory.GiftsReceived)
+	if assert.Len(t, colleague.Orders, 1) {
+		assert.Equal(t, "colleague", colleague.Orders[0].Recipient)
+	}
+
+	w = request("GET", "/api/info", "buyer")
+	assert.Contains(t, w.Body.String(), `"giftsReceived":[]`)
+}
+
+func TestOrdersHandlers(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("buyer", "hash", 1000)
+	testDB.PutUser("manager", "hash", 0)
+	_, err := testDB.SetUserRole(ctx, "manager", models.RoleManager)
+	assert.NoError(t, err)
+
+	request := func(method, path, username, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", generateAuthToken(username))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("POST", "/api/buy/t-shirt?size=M", "buyer", "").Code)
+	assert.Equal(t, http.StatusOK, request("POST", "/api/buy/cup", "buyer", "").Code)
+
+	w := request("GET", "/api/orders", "buyer", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, http.StatusForbidden, request("POST", "/api/orders/1/status", "buyer", `{"status": "packed"}`).Code)
+
+	w = request("GET", "/api/orders?status=placed", "manager", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var orders models.OrdersResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &orders))
+	if assert.Len(t, orders.Orders, 2) {
+		assert.Equal(t, "buyer", orders.Orders[0].Username)
+		assert.Equal(t, "t-shirt", orders.Orders[0].Item)
+		assert.Equal(t, "M", orders.Orders[0].Size)
+		assert.Equal(t, models.OrderPlaced, orders.Orders[0].Status)
+	}
+	assert.Equal(t, http.StatusBadRequest, request("GET", "/api/orders?status=lost", "manager", "").Code)
+
+	w = request("POST", "/api/orders/1/status", "manager", `{"status": "packed"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var order models.Order
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+	assert.Equal(t, models.OrderPacked, order.Status)
+
+	w = request("POST", "/api/orders/2/status", "manager", `{"status": "handed_over"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Order status can't be changed")
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/orders/2/status", "manager", `{"status": "lost"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/orders/two/status", "manager", `{"status": "packed"}`).Code)
+	assert.Equal(t, http.StatusNotFound, request("POST", "/api/orders/3/status", "manager", `{"status": "packed"}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request("GET", "/api/orders/1/status", "manager", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request("POST", "/api/orders", "manager", "").Code)
+
+	w = request("GET", "/api/info", "buyer", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var info models.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	if assert.Len(t, info.Orders, 2) {
+		assert.Equal(t, models.OrderPacked, info.Orders[0].Status)
+		assert.Equal(t, models.OrderPlaced, info.Orders[1].Status)
+	}
+
+	w = request("GET", "/api/info", "manager", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"orders":[]`)
+}
+
+func TestCancelOrderHandler(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("buyer", "hash", 1000)
+	testDB.PutUser("other", "hash", 1000)
+
+	request := func(method, path, username string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", generateAuthToken(username))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("POST", "/api/buy/t-shirt?size=M", "buyer").Code)
+	assert.Equal(t, http.StatusOK, request("POST", "/api/buy/cup", "buyer").Code)
+
+	assert.Equal(t, http.StatusNotFound, request("POST", "/api/orders/1/cancel", "other").Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/orders/first/cancel", "buyer").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, request("GET", "/api/orders/1/cancel", "buyer").Code)
+
+	w := request("POST", "/api/orders/1/cancel", "buyer")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var order models.Order
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+	assert.Equal(t, models.OrderCancelled, order.Status)
+
+	w = request("POST", "/api/orders/1/cancel", "buyer")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Order status can't be changed")
+
+	handler.Orders.RefundWindow = time.Nanosecond
+	w = request("POST", "/api/orders/2/cancel", "buyer")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "refund window has passed")
+
+	w = request("GET", "/api/info", "buyer")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var info models.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, models.Coins(980), info.Coins)
+	assert.Equal(t, []models.RefundInfo{{OrderID: 1, Item: "t-shirt", Size: "M", Amount: 80, Reason: db.UserCancelReason}},
+		info.CoinHistory.Refunds)
+	assert.Equal(t, []models.InventoryInfo{{Type: "cup", Quantity: 1}}, info.Inventory)
+
+	w = request("GET", "/api/info", "other")
+	assert.Contains(t, w.Body.String(), `"refunds":[]`)
+}
+
+func TestHealthzHandler(t *testing.T) {
+	resetDB()
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReadyzHandler(t *testing.T) {
+	resetDB()
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.HealthResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", response.Status)
+}
+
+func TestReadyzHandler_ShuttingDown(t *testing.T) {
+	resetDB()
+
+	shuttingDownHandler := NewHandler(testDB)
+	shuttingDownHandler.SetShuttingDown()
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	shuttingDownHandler.ReadyzHandler(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "in progress")
+}
+
+func TestSendCoinHandler_RecipientOverflow(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("sender", "hash", 100)
+	testDB.PutUser("receiver", "hash", math.MaxInt64-10)
+
+	reqBytes, _ := json.Marshal(models.SendCoinRequest{ToUser: "receiver", Amount: 20})
+	req, _ := http.NewRequest("POST", "/api/sendCoin", bytes.NewBuffer(reqBytes))
+	req.Header.Set("Authorization", generateAuthToken("sender"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	sender, _ := testDB.GetUserByUsername(ctx, "sender")
+	assert.Equal(t, models.Coins(100), sender.Coins)
+}
+
+func decodeErrors(t *testing.T, w *httptest.ResponseRecorder) models.ErrorResponse {
+	t.Helper()
+
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var response models.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestAuthHandler_EmptyUsername(t *testing.T) {
+	resetDB()
+
+	reqBytes, _ := json.Marshal(models.AuthRequest{Username: "", Password: "password"})
+	req, _ := http.NewRequest("POST", "/api/auth", bytes.NewBuffer(reqBytes))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response := decodeErrors(t, w)
+	assert.Empty(t, response.Code)
+	assert.Len(t, response.Errors, 1)
+	assert.Equal(t, "username", response.Errors[0].Field)
+
+	_, err := testDB.GetUserByUsername(ctx, "")
+	assert.Error(t, err)
+}
+
+func TestSendCoinHandler_InvalidRequests(t *testing.T) {
+	resetDB()
+
+	testDB.PutUser("sender", "hash", 100)
+	testDB.PutUser("receiver", "hash", 50)
+
+	cases := map[string]struct {
+		body  string
+		field string
+	}{
+		"zero amount":     {body: `{"toUser": "receiver", "amount": 0}`, field: "amount"},
+		"negative amount": {body: `{"toUser": "receiver", "amount": -10}`, field: "amount"},
+		"no recipient":    {body: `{"amount": 10}`, field: "toUser"},
+		"self transfer":   {body: `{"toUser": "sender", "amount": 10}`, field: "toUser"},
+		"malformed body":  {body: `{"toUser": "receiver", "amount": 1.5}`},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/sendCoin", bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", generateAuthToken("sender"))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			response := decodeErrors(t, w)
+			assert.NotEmpty(t, response.Errors)
+			assert.Equal(t, tc.field, response.Errors[0].Field)
+		})
+	}
+
+	sender, _ := testDB.GetUserByUsername(ctx, "sender")
+	assert.Equal(t, models.Coins(100), sender.Coins)
+}
+
+func TestInfoHandler_UnauthorizedJSON(t *testing.T) {
+	resetDB()
+
+	req, _ := http.NewRequest("GET", "/api/info", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	response := decodeErrors(t, w)
+	assert.Equal(t, "Unauthorized", response.Errors[0].Message)
+}
+
+type unavailableDB struct {
+	db.DB
+}
+
+func (unavailableDB) GetUserByUsername(_ context.Context, _ string) (*models.User, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unavailableDB) Ping(_ context.Context) error {
+	return errors.New("dial tcp db.internal:5432: connection refused")
+}
+
+type pendingMigrationsDB struct {
+	*db.MemoryDatabase
+}
+
+func (pendingMigrationsDB) CheckSchema(_ context.Context) error {
+	return errors.New("schema version is 3, expected 10")
+}
+
+func TestReadyzHandler_HidesErrors(t *testing.T) {
+	for name, database := range map[string]db.DB{
+		"database":   unavailableDB{},
+		"migrations": pendingMigrationsDB{testDB},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/readyz", nil)
+			w := httptest.NewRecorder()
+			NewHandler(database).ReadyzHandler(w, req)
+
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+			var response models.HealthResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "unavailable", response.Status)
+			assert.Equal(t, map[string]string{"database": "unavailable", "migrations": "pending"}[name], response.Checks[name])
+			assert.NotContains(t, w.Body.String(), "db.internal")
+			assert.NotContains(t, w.Body.String(), "schema version")
+		})
+	}
+}
+
+func TestHandlers_DatabaseOutageIsNotNotFound(t *testing.T) {
+	outageHandler := NewHandler(unavailableDB{})
+	token := generateAuthToken("testuser")
+
+	for name, serve := range map[string]http.HandlerFunc{
+		"info": outageHandler.InfoHandler,
+		"buy":  outageHandler.BuyHandler,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req = mux.SetURLVars(req, map[string]string{"item": "cup"})
+			req.Header.Set("Authorization", token)
+			w := httptest.NewRecorder()
+			serve(w, req)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.NotContains(t, w.Body.String(), "not found")
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"merch_store/internal/logging"
+	"merch_store/internal/models"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+	statusPending     = "pending"
+)
+
+// HealthzHandler handles /healthz, it reports that process is alive...
+func (h *Handler) HealthzHandler(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, http.StatusOK, models.HealthResponse{Status: statusOK})
+}
+
+// ReadyzHandler handles /readyz, it reports whether server can accept traffic...
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	checks := map[string]string{
+		"database":   statusOK,
+		"migrations": statusOK,
+		"shutdown":   statusOK,
+	}
+	ready := true
+
+	if h.shuttingDown.Load() {
+		checks["shutdown"] = "in progress"
+		ready = false
+	}
+
+	// /readyz is public, so errors go to logs and checks only tell what is wrong
+	if err := h.DB.Ping(ctx); err != nil {
+		logging.FromContext(ctx).Warn("database is unavailable", slog.Any("error", err))
+		checks["database"] = statusUnavailable
+		checks["migrations"] = "unknown"
+		ready = false
+	} else if err := h.DB.CheckSchema(ctx); err != nil {
+		logging.FromContext(ctx).Warn("migrations are not applied", slog.Any("error", err))
+		checks["migrations"] = statusPending
+		ready = false
+	}
+
+	if !ready {
+		writeHealth(w, http.StatusServiceUnavailable, models.HealthResponse{Status: statusUnavailable, Checks: checks})
+		return
+	}
+	writeHealth(w, http.StatusOK, models.HealthResponse{Status: statusOK, Checks: checks})
+}
+
+// SetShuttingDown makes /readyz fail so that traffic is drained before server stops...
+func (h *Handler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+func writeHealth(w http.ResponseWriter, status int, response models.HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"merch_store/internal/models"
+	"merch_store/internal/validation"
+
+	"github.com/gorilla/mux"
+)
+
+// OrdersHandler handles /api/orders, status query parameter filters orders...
+func (h *Handler) OrdersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims, err := h.TokenValidator.ValidateToken(r.Header.Get("Authorization"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status := models.OrderStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.Valid() {
+		writeValidationError(w, models.OrderStatusRequest{Status: status}.Validate())
+		return
+	}
+
+	response, err := h.Orders.List(ctx, claims.Username, status)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	writeJSON(ctx, w, http.StatusOK, response)
+}
+
+// OrderStatusHandler handles /api/orders/{id}/status...
+func (h *Handler) OrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims, err := h.TokenValidator.ValidateToken(r.Header.Get("Authorization"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.OrderStatusRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	order, err := h.Orders.SetStatus(ctx, claims.Username, id, req.Status)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	writeJSON(ctx, w, http.StatusOK, order)
+}
+
+// CancelOrderHandler handles /api/orders/{id}/cancel, users cancel their own orders and get coins back...
+func (h *Handler) CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims, err := h.TokenValidator.ValidateToken(r.Header.Get("Authorization"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.Orders.Cancel(ctx, claims.Username, id)
+	if err != nil {
+		respondError(ctx, w, err)
+		return
+	}
+
+	writeJSON(ctx, w, http.StatusOK, order)
+}
+
+// orderID parses order ID from URL, error response is written when it's not valid...
+func orderID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		writeValidationError(w, validation.Errors{{Field: "id", Message: "must be positive integer"}})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"merch_store/internal/db"
+	"merch_store/internal/httperror"
+	"merch_store/internal/logging"
+	"merch_store/internal/models"
+	"merch_store/internal/service"
+	"merch_store/internal/validation"
+)
+
+// writeJSON responds with status and response encoded as JSON...
+func writeJSON(ctx context.Context, w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.FromContext(ctx).Error("failed to encode response", slog.Any("error", err))
+	}
+}
+
+// writeError responds with status and error body containing message...
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeCodedError(w, status, "", message)
+}
+
+// writeCodedError responds with status and error body containing code and message...
+func writeCodedError(w http.ResponseWriter, status int, code models.ErrorCode, message string) {
+	httperror.Write(w, status, code, []validation.FieldError{{Message: message}})
+}
+
+// writeValidationError responds with 400 and all field errors found in err...
+func writeValidationError(w http.ResponseWriter, err error) {
+	errs, ok := validation.AsErrors(err)
+	if !ok {
+		errs = validation.Errors{{Message: err.Error()}}
+	}
+	httperror.Write(w, http.StatusBadRequest, "", errs)
+}
+
+// internalError logs err with request scoped logger and responds with 500...
+func internalError(ctx context.Context, w http.ResponseWriter, message string, err error) {
+	logging.FromContext(ctx).Error(message, slog.Any("error", err))
+	writeError(w, http.StatusInternalServerError, message)
+}
+
+// respondError maps error returned by services or db to response...
+func respondError(ctx context.Context, w http.ResponseWriter, err error) {
+	if _, ok := validation.AsErrors(err); ok {
+		writeValidationError(w, err)
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword):
+		writeError(w, http.StatusUnauthorized, "Invalid password")
+	case errors.Is(err, service.ErrRecipientNotFound):
+		writeError(w, http.StatusNotFound, "Recipient not found")
+	case errors.Is(err, service.ErrItemNotFound):
+		writeError(w, http.StatusNotFound, "Item not found")
+	case errors.Is(err, models.ErrVariantNotFound):
+		writeCodedError(w, http.StatusNotFound, models.CodeVariantNotFound, "Variant not found, choose size and color from catalog")
+	case errors.Is(err, service.ErrForbidden):
+		writeError(w, http.StatusForbidden, "Only store managers can manage orders")
+	case errors.Is(err, service.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, db.ErrNotFound):
+		writeError(w, http.StatusNotFound, "Not found")
+	case errors.Is(err, db.ErrInsufficientFunds):
+		writeCodedError(w, http.StatusBadRequest, models.CodeInsufficientFunds, "Insufficient coins")
+	case errors.Is(err, db.ErrOutOfStock):
+		writeCodedError(w, http.StatusBadRequest, models.CodeOutOfStock, "Item is out of stock")
+	case errors.Is(err, models.ErrLimitExceeded):
+		writeCodedError(w, http.StatusBadRequest, models.CodeLimitExceeded, "Purchase limit exceeded")
+	case errors.Is(err, models.ErrStatusTransition):
+		writeCodedError(w, http.StatusBadRequest, models.CodeStatusTransition, "Order status can't be changed, orders go from placed to packed and handed over or get cancelled")
+	case errors.Is(err, models.ErrRefundExpired):
+		writeCodedError(w, http.StatusBadRequest, models.CodeRefundExpired, "Order can't be cancelled anymore, refund window has passed")
+	case errors.Is(err, models.ErrCoinsOverflow):
+		writeCodedError(w, http.StatusBadRequest, models.CodeCoinsOverflow, "Amount is too large for recipient")
+	case errors.Is(err, db.ErrConflict):
+		writeError(w, http.StatusConflict, "Request conflicts with concurrent update, please retry")
+	case errors.Is(err, db.ErrConstraintViolation):
+		writeError(w, http.StatusUnprocessableEntity, "Request violates data constraints")
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		logging.FromContext(ctx).Warn("request interrupted", slog.Any("error", err))
+		writeError(w, http.StatusServiceUnavailable, "Request timed out")
+	default:
+		internalError(ctx, w, "Internal server error", err)
+	}
+}
+
+// decodeRequest decodes JSON body into req and validates it, error response is written on failure...
+func decodeRequest(w http.ResponseWriter, r *http.Request, req interface{ Validate() error }) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return false
+	}
+	return true
+}
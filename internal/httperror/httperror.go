@@ -0,0 +1,23 @@
+// Package httperror - package for writing JSON error responses shared by handlers and middlewares.
+package httperror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"merch_store/internal/models"
+	"merch_store/internal/validation"
+)
+
+// Write responds with status and error body containing code and errs, code is empty for errors
+// that are told apart by status alone...
+func Write(w http.ResponseWriter, status int, code models.ErrorCode, errs []validation.FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.ErrorResponse{Code: code, Errors: errs})
+}
+
+// WriteMessage responds with status and error body containing message...
+func WriteMessage(w http.ResponseWriter, status int, message string) {
+	Write(w, status, "", []validation.FieldError{{Message: message}})
+}
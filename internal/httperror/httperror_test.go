@@ -0,0 +1,29 @@
+package httperror
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"merch_store/internal/models"
+	"merch_store/internal/validation"
+)
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, http.StatusBadRequest, models.CodeOutOfStock, []validation.FieldError{{Message: "Item is out of stock"}})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code": "out_of_stock", "errors": [{"message": "Item is out of stock"}]}`, w.Body.String())
+}
+
+func TestWriteMessage(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteMessage(w, http.StatusConflict, "Request is in progress")
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "Request is in progress"}]}`, w.Body.String())
+}
@@ -0,0 +1,203 @@
+// Package idempotency makes retried POST requests with the same Idempotency-Key safe by replaying first response.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"merch_store/internal/httperror"
+)
+
+// Header is request header with key generated by client, ReplayedHeader is set on replayed responses.
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// DefaultTTL is how long responses are kept for replay.
+const DefaultTTL = 24 * time.Hour
+
+// MaxKeyLength limits length of Idempotency-Key header.
+const MaxKeyLength = 255
+
+type state int
+
+const (
+	stateNew state = iota
+	stateInProgress
+	stateMismatch
+	stateDone
+)
+
+type entry struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expiresAt   time.Time
+}
+
+// Store keeps responses in memory, so keys are only honoured by the instance that served the first request...
+type Store struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*entry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// NewStore creates store keeping responses for ttl...
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, entries: make(map[string]*entry), now: time.Now}
+}
+
+// begin reserves key for request with fingerprint, completed entry is returned for replay...
+func (s *Store) begin(key string, fingerprint [sha256.Size]byte) (state, *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if e.done && now.After(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	e, ok := s.entries[key]
+	if ok && e.done && now.After(e.expiresAt) {
+		ok = false
+	}
+	switch {
+	case !ok:
+		s.entries[key] = &entry{fingerprint: fingerprint}
+		return stateNew, nil
+	case e.fingerprint != fingerprint:
+		return stateMismatch, nil
+	case !e.done:
+		return stateInProgress, nil
+	default:
+		return stateDone, e
+	}
+}
+
+func (s *Store) finish(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.done, e.status, e.header, e.body = true, status, header, body
+		e.expiresAt = s.now().Add(s.ttl)
+	}
+}
+
+func (s *Store) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// Middleware replays response for POST request whose Idempotency-Key was already used by the same user,
+// scope returns the user and requests it returns empty string for are not cached. Responses with 5xx status
+// are not kept, so request that failed on server can be retried with the same key...
+func Middleware(store *Store, scope func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				httperror.WriteMessage(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			user := scope(r)
+			if user == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				httperror.WriteMessage(w, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := user + "\x00" + key
+			switch state, e := store.begin(storeKey, fingerprint(r, body)); state {
+			case stateInProgress:
+				httperror.WriteMessage(w, http.StatusConflict, "Request with this Idempotency-Key is in progress")
+				return
+			case stateMismatch:
+				httperror.WriteMessage(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for another request")
+				return
+			case stateDone:
+				for name, values := range e.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(e.status)
+				_, _ = w.Write(e.body)
+				return
+			}
+
+			recorder := &recorder{ResponseWriter: w, status: http.StatusOK}
+			finished := false
+			defer func() {
+				if !finished {
+					store.forget(storeKey)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			store.finish(storeKey, recorder.status, w.Header().Clone(), recorder.body.Bytes())
+			finished = true
+		})
+	}
+}
+
+func fingerprint(r *http.Request, body []byte) [sha256.Size]byte {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	_, _ = hash.Write(body)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// recorder passes response through and keeps copy of it...
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,135 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(store *Store, status int, calls *atomic.Int32) http.Handler {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("X-Call", strconv.Itoa(int(n)))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("done"))
+	})
+	scope := func(r *http.Request) string { return r.Header.Get("Authorization") }
+	return Middleware(store, scope)(next)
+}
+
+func send(handler http.Handler, method, body, user, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/sendCoin", strings.NewReader(body))
+	req.Header.Set("Authorization", user)
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_ReplaysResponse(t *testing.T) {
+	var calls atomic.Int32
+	handler := newTestHandler(NewStore(time.Hour), http.StatusOK, &calls)
+
+	first := send(handler, http.MethodPost, `{"amount": 1}`, "alice", "key-1")
+	second := send(handler, http.MethodPost, `{"amount": 1}`, "alice", "key-1")
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "done", second.Body.String())
+	assert.Equal(t, first.Header().Get("X-Call"), second.Header().Get("X-Call"))
+	assert.Equal(t, "true", second.Header().Get(ReplayedHeader))
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_KeysAreScopedByUser(t *testing.T) {
+	var calls atomic.Int32
+	handler := newTestHandler(NewStore(time.Hour), http.StatusOK, &calls)
+
+	send(handler, http.MethodPost, `{}`, "alice", "key-1")
+	send(handler, http.MethodPost, `{}`, "bob", "key-1")
+	send(handler, http.MethodPost, `{}`, "", "key-1")
+	send(handler, http.MethodPost, `{}`, "", "key-1")
+
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestMiddleware_PassesRequestsWithoutKey(t *testing.T) {
+	var calls atomic.Int32
+	handler := newTestHandler(NewStore(time.Hour), http.StatusOK, &calls)
+
+	send(handler, http.MethodPost, `{}`, "alice", "")
+	send(handler, http.MethodPost, `{}`, "alice", "")
+	send(handler, http.MethodGet, ``, "alice", "key-1")
+	send(handler, http.MethodGet, ``, "alice", "key-1")
+
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestMiddleware_RejectsReusedKeyForAnotherRequest(t *testing.T) {
+	var calls atomic.Int32
+	handler := newTestHandler(NewStore(time.Hour), http.StatusOK, &calls)
+
+	send(handler, http.MethodPost, `{"amount": 1}`, "alice", "key-1")
+	w := send(handler, http.MethodPost, `{"amount": 2}`, "alice", "key-1")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestMiddleware_DoesNotKeepServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	handler := newTestHandler(NewStore(time.Hour), http.StatusServiceUnavailable, &calls)
+
+	send(handler, http.MethodPost, `{}`, "alice", "key-1")
+	send(handler, http.MethodPost, `{}`, "alice", "key-1")
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddleware_RejectsConcurrentRequest(t *testing.T) {
+	store := NewStore(time.Hour)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Middleware(store, func(*http.Request) string { return "alice" })(next)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		send(handler, http.MethodPost, `{}`, "alice", "key-1")
+	}()
+	<-started
+
+	w := send(handler, http.MethodPost, `{}`, "alice", "key-1")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	close(release)
+	<-done
+}
+
+func TestStore_ExpiresEntries(t *testing.T) {
+	store := NewStore(time.Minute)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	var calls atomic.Int32
+	handler := newTestHandler(store, http.StatusOK, &calls)
+
+	send(handler, http.MethodPost, `{}`, "alice", "key-1")
+	now = now.Add(2 * time.Minute)
+	send(handler, http.MethodPost, `{}`, "alice", "key-1")
+
+	assert.Equal(t, int32(2), calls.Load())
+}
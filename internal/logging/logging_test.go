@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext_Default(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+}
+
+func TestMiddleware_PropagatesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	base := New(&buf, slog.LevelInfo)
+
+	var seenID string
+	handler := Middleware(base)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		seenID = RequestIDFromContext(r.Context())
+		FromContext(r.Context()).Info("inside handler")
+	}))
+
+	req, _ := http.NewRequest("GET", "/api/info", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc-123", seenID)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		var entry map[string]any
+		assert.NoError(t, json.Unmarshal(line, &entry))
+		assert.Equal(t, "abc-123", entry["request_id"])
+	}
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	handler := Middleware(New(&bytes.Buffer{}, slog.LevelInfo))(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+
+	req, _ := http.NewRequest("GET", "/api/info", nil)
+	req.Header.Set(RequestIDHeader, "bad id with spaces")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+}
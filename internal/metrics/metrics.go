@@ -0,0 +1,100 @@
+// Package metrics - package for exposing Prometheus metrics of the store.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "merch_store"
+
+// Registry contains all metrics exposed by the server...
+var Registry = prometheus.NewRegistry()
+
+var (
+	coinsTransferred = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coins_transferred_total",
+		Help:      "Total amount of coins sent between users.",
+	})
+	transfers = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Total number of successful coin transfers.",
+	})
+	purchases = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "purchases_total",
+		Help:      "Total number of purchased merch items.",
+	}, []string{"item"})
+	insufficientFunds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "insufficient_funds_total",
+		Help:      "Total number of operations rejected because user did not have enough coins.",
+	}, []string{"operation"})
+	outOfStock = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "out_of_stock_total",
+		Help:      "Total number of purchases rejected because item was sold out.",
+	}, []string{"item"})
+	orderStatuses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_status_changes_total",
+		Help:      "Total number of orders moved to status, by is manager for store managers and user for cancellations by buyers.",
+	}, []string{"status", "by"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		coinsTransferred,
+		transfers,
+		purchases,
+		insufficientFunds,
+		outOfStock,
+		orderStatuses,
+	)
+}
+
+// Handler returns HTTP handler serving /metrics...
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveTransfer records successful transfer of amount coins...
+func ObserveTransfer(amount int64) {
+	transfers.Inc()
+	coinsTransferred.Add(float64(amount))
+}
+
+// ObservePurchase records successful purchase of item...
+func ObservePurchase(item string) {
+	purchases.WithLabelValues(item).Inc()
+}
+
+// ObserveInsufficientFunds records operation rejected because of insufficient coins...
+func ObserveInsufficientFunds(operation string) {
+	insufficientFunds.WithLabelValues(operation).Inc()
+}
+
+// ObserveOutOfStock records purchase rejected because item was sold out...
+func ObserveOutOfStock(item string) {
+	outOfStock.WithLabelValues(item).Inc()
+}
+
+// Who changed order status, values of by label of order status changes.
+const (
+	ByManager = "manager"
+	ByUser    = "user"
+)
+
+// ObserveOrderStatus records order moved to status by store manager or user...
+func ObserveOrderStatus(status, by string) {
+	orderStatuses.WithLabelValues(status, by).Inc()
+}
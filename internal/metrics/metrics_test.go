@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_RecordsRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/api/buy/{item}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("/api/buy/{item}", "POST", "404"))
+
+	req, _ := http.NewRequest("POST", "/api/buy/cup", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	after := testutil.ToFloat64(httpRequests.WithLabelValues("/api/buy/{item}", "POST", "404"))
+	assert.Equal(t, before+1, after)
+}
+
+func TestBusinessCounters(t *testing.T) {
+	coinsBefore := testutil.ToFloat64(coinsTransferred)
+	ObserveTransfer(30)
+	assert.Equal(t, coinsBefore+30, testutil.ToFloat64(coinsTransferred))
+
+	purchasesBefore := testutil.ToFloat64(purchases.WithLabelValues("cup"))
+	ObservePurchase("cup")
+	assert.Equal(t, purchasesBefore+1, testutil.ToFloat64(purchases.WithLabelValues("cup")))
+
+	rejectionsBefore := testutil.ToFloat64(insufficientFunds.WithLabelValues("buy"))
+	ObserveInsufficientFunds("buy")
+	assert.Equal(t, rejectionsBefore+1, testutil.ToFloat64(insufficientFunds.WithLabelValues("buy")))
+
+	soldOutBefore := testutil.ToFloat64(outOfStock.WithLabelValues("pink-hoody"))
+	ObserveOutOfStock("pink-hoody")
+	assert.Equal(t, soldOutBefore+1, testutil.ToFloat64(outOfStock.WithLabelValues("pink-hoody")))
+
+	packedBefore := testutil.ToFloat64(orderStatuses.WithLabelValues("packed", ByManager))
+	cancelledBefore := testutil.ToFloat64(orderStatuses.WithLabelValues("cancelled", ByUser))
+	ObserveOrderStatus("packed", ByManager)
+	assert.Equal(t, packedBefore+1, testutil.ToFloat64(orderStatuses.WithLabelValues("packed", ByManager)))
+	assert.Equal(t, cancelledBefore, testutil.ToFloat64(orderStatuses.WithLabelValues("cancelled", ByUser)))
+}
+
+func TestHandler_ExposesMetrics(t *testing.T) {
+	ObservePurchase("pen")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	Handler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `merch_store_purchases_total{item="pen"}`))
+}
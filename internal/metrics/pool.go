@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports pgxpool statistics...
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns           *prometheus.Desc
+	idleConns               *prometheus.Desc
+	constructingConns       *prometheus.Desc
+	totalConns              *prometheus.Desc
+	maxConns                *prometheus.Desc
+	acquireCount            *prometheus.Desc
+	acquireDuration         *prometheus.Desc
+	emptyAcquireCount       *prometheus.Desc
+	canceledAcquireCount    *prometheus.Desc
+	newConnsCount           *prometheus.Desc
+	maxLifetimeDestroyCount *prometheus.Desc
+	maxIdleDestroyCount     *prometheus.Desc
+}
+
+// NewPoolCollector generates PoolCollector...
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &PoolCollector{
+		pool:                    pool,
+		acquiredConns:           desc("acquired_connections", "Number of currently acquired connections."),
+		idleConns:               desc("idle_connections", "Number of currently idle connections."),
+		constructingConns:       desc("constructing_connections", "Number of connections being established."),
+		totalConns:              desc("total_connections", "Total number of connections in the pool."),
+		maxConns:                desc("max_connections", "Maximum size of the pool."),
+		acquireCount:            desc("acquires_total", "Total number of successful acquires."),
+		acquireDuration:         desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquireCount:       desc("empty_acquires_total", "Total number of acquires that waited for a connection."),
+		canceledAcquireCount:    desc("canceled_acquires_total", "Total number of acquires canceled by context."),
+		newConnsCount:           desc("new_connections_total", "Total number of opened connections."),
+		maxLifetimeDestroyCount: desc("max_lifetime_destroys_total", "Total number of connections closed due to max lifetime."),
+		maxIdleDestroyCount:     desc("max_idle_destroys_total", "Total number of connections closed due to max idle time."),
+	}
+}
+
+// Describe implements prometheus.Collector...
+func (pc *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(pc, ch)
+}
+
+// Collect implements prometheus.Collector...
+func (pc *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := pc.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(pc.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(pc.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(pc.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(pc.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(pc.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(pc.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pc.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(pc.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pc.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pc.newConnsCount, prometheus.CounterValue, float64(stat.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(pc.maxLifetimeDestroyCount, prometheus.CounterValue, float64(stat.MaxLifetimeDestroyCount()))
+	ch <- prometheus.MustNewConstMetric(pc.maxIdleDestroyCount, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()))
+}
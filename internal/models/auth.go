@@ -16,19 +16,23 @@ type AuthResponse struct {
 
 // InfoResponse - Response of /api/info...
 type InfoResponse struct {
-	Coins       int             `json:"coins"`
+	Coins       Coins           `json:"coins"`
 	Inventory   []InventoryInfo `json:"inventory"`
 	CoinHistory CoinHistory     `json:"coinHistory"`
+	Orders      []Order         `json:"orders"`
 }
 
-// CoinHistory - History of user transactions...
+// CoinHistory - History of user transactions, refunds of cancelled orders and merch gifts...
 type CoinHistory struct {
-	Received []TransactionInfo `json:"received"`
-	Sent     []TransactionInfo `json:"sent"`
+	Received      []TransactionInfo `json:"received"`
+	Sent          []TransactionInfo `json:"sent"`
+	Refunds       []RefundInfo      `json:"refunds"`
+	GiftsSent     []GiftInfo        `json:"giftsSent"`
+	GiftsReceived []GiftInfo        `json:"giftsReceived"`
 }
 
 // SendCoinRequest - request of /api/sendCoin...
 type SendCoinRequest struct {
 	ToUser string `json:"toUser"`
-	Amount int    `json:"amount"`
+	Amount Coins  `json:"amount"`
 }
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrCoinsOverflow is returned when arithmetic on coins does not fit into Coins...
+var ErrCoinsOverflow = errors.New("coins overflow")
+
+// Coins is amount of store currency, it is stored in BIGINT columns...
+type Coins int64
+
+// Add returns c + other or ErrCoinsOverflow...
+func (c Coins) Add(other Coins) (Coins, error) {
+	if (other > 0 && c > math.MaxInt64-other) || (other < 0 && c < math.MinInt64-other) {
+		return 0, ErrCoinsOverflow
+	}
+	return c + other, nil
+}
+
+// Sub returns c - other or ErrCoinsOverflow...
+func (c Coins) Sub(other Coins) (Coins, error) {
+	if (other < 0 && c > math.MaxInt64+other) || (other > 0 && c < math.MinInt64+other) {
+		return 0, ErrCoinsOverflow
+	}
+	return c - other, nil
+}
+
+// Mul returns c * n or ErrCoinsOverflow...
+func (c Coins) Mul(n int64) (Coins, error) {
+	if c == 0 || n == 0 {
+		return 0, nil
+	}
+	result := int64(c) * n
+	if result/n != int64(c) || (c == -1 && n == math.MinInt64) || (n == -1 && c == math.MinInt64) {
+		return 0, ErrCoinsOverflow
+	}
+	return Coins(result), nil
+}
+
+// MarshalJSON encodes coins as JSON integer...
+func (c Coins) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(c), 10), nil
+}
+
+// UnmarshalJSON decodes coins from JSON integer, fractions and out of range values are rejected...
+func (c *Coins) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	value, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Errorf("coins value %s: %w", data, ErrCoinsOverflow)
+		}
+		return fmt.Errorf("coins value %s must be an integer", data)
+	}
+
+	*c = Coins(value)
+	return nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoins_Add(t *testing.T) {
+	sum, err := Coins(80).Add(20)
+	assert.NoError(t, err)
+	assert.Equal(t, Coins(100), sum)
+
+	_, err = Coins(math.MaxInt64).Add(1)
+	assert.ErrorIs(t, err, ErrCoinsOverflow)
+
+	_, err = Coins(math.MinInt64).Add(-1)
+	assert.ErrorIs(t, err, ErrCoinsOverflow)
+}
+
+func TestCoins_Sub(t *testing.T) {
+	diff, err := Coins(80).Sub(100)
+	assert.NoError(t, err)
+	assert.Equal(t, Coins(-20), diff)
+
+	_, err = Coins(math.MinInt64).Sub(1)
+	assert.ErrorIs(t, err, ErrCoinsOverflow)
+
+	_, err = Coins(0).Sub(math.MinInt64)
+	assert.ErrorIs(t, err, ErrCoinsOverflow)
+}
+
+func TestCoins_Mul(t *testing.T) {
+	product, err := Coins(500).Mul(3)
+	assert.NoError(t, err)
+	assert.Equal(t, Coins(1500), product)
+
+	product, err = Coins(0).Mul(math.MaxInt64)
+	assert.NoError(t, err)
+	assert.Equal(t, Coins(0), product)
+
+	_, err = Coins(math.MaxInt64 / 2).Mul(3)
+	assert.ErrorIs(t, err, ErrCoinsOverflow)
+
+	_, err = Coins(math.MinInt64).Mul(-1)
+	assert.ErrorIs(t, err, ErrCoinsOverflow)
+}
+
+func TestCoins_JSON(t *testing.T) {
+	data, err := json.Marshal(SendCoinRequest{ToUser: "bob", Amount: 42})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"toUser": "bob", "amount": 42}`, string(data))
+
+	var req SendCoinRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{"toUser": "bob", "amount": 42}`), &req))
+	assert.Equal(t, Coins(42), req.Amount)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 4.2}`), &req))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": "42"}`), &req))
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount": 99999999999999999999}`), &req), ErrCoinsOverflow)
+}
@@ -0,0 +1,7 @@
+package models
+
+// HealthResponse - Response of /healthz and /readyz...
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
@@ -10,5 +10,7 @@ type Inventory struct {
 // InventoryInfo contains inventory information that we send inside response to user...
 type InventoryInfo struct {
 	Type     string `json:"type"`
+	Size     string `json:"size,omitempty"`
+	Color    string `json:"color,omitempty"`
 	Quantity int    `json:"quantity"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Kinds of ledger entries.
+const (
+	LedgerTransferIn  = "transfer_in"
+	LedgerTransferOut = "transfer_out"
+	LedgerPurchase    = "purchase"
+	LedgerAdjustment  = "adjustment"
+	LedgerRefund      = "refund"
+)
+
+// LedgerEntry is one change of user balance, Amount is negative when coins were spent...
+type LedgerEntry struct {
+	Time     time.Time `json:"time"`
+	Username string    `json:"username"`
+	Kind     string    `json:"kind"`
+	Subject  string    `json:"subject,omitempty"`
+	Amount   Coins     `json:"amount"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// BalanceMismatch describes user whose balance differs from one computed from ledger...
+type BalanceMismatch struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Coins    Coins  `json:"coins"`
+	Expected Coins  `json:"expected"`
+}
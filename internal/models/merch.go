@@ -1,8 +1,202 @@
 package models
 
-// Merch contains information about merch item in store...
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLimitExceeded is returned when user has already bought as many items as purchase limit allows...
+var ErrLimitExceeded = errors.New("purchase limit exceeded")
+
+// ErrVariantNotFound is returned when merch has no variant with requested options...
+var ErrVariantNotFound = errors.New("variant not found")
+
+// Merch contains information about merch item in store, nil Stock means supply is unlimited
+// and nil Limit means user can buy any number of items, merch with Variants can only be bought as one of them,
+// the first one is bought when no options are given...
 type Merch struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Price int    `json:"price"`
+	ID       int            `json:"id"`
+	Name     string         `json:"name"`
+	Price    Coins          `json:"price"`
+	Stock    *int           `json:"stock,omitempty"`
+	Limit    *PurchaseLimit `json:"limit,omitempty"`
+	Variants []MerchVariant `json:"variants,omitempty"`
+}
+
+// InStock reports whether at least one item can be bought...
+func (m *Merch) InStock() bool {
+	return m.Stock == nil || *m.Stock > 0
+}
+
+// Validate checks price, stock, limit and variants of merch...
+func (m *Merch) Validate() error {
+	if m.Price <= 0 {
+		return fmt.Errorf("%s has non-positive price %d", m.Name, m.Price)
+	}
+	if m.Stock != nil && *m.Stock < 0 {
+		return fmt.Errorf("%s has negative stock %d", m.Name, *m.Stock)
+	}
+	if err := m.Limit.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", m.Name, err)
+	}
+
+	seen := make(map[VariantOptions]bool, len(m.Variants))
+	for _, variant := range m.Variants {
+		price, err := m.PriceOf(&variant)
+		switch {
+		case variant.VariantOptions == VariantOptions{}:
+			return fmt.Errorf("%s has variant without size and color", m.Name)
+		case seen[variant.VariantOptions]:
+			return fmt.Errorf("%s variant %s is duplicated", m.Name, variant.VariantOptions)
+		case err != nil:
+			return fmt.Errorf("%s variant %s: %w", m.Name, variant.VariantOptions, err)
+		case price <= 0:
+			return fmt.Errorf("%s variant %s has non-positive price %d", m.Name, variant.VariantOptions, price)
+		case variant.Stock != nil && *variant.Stock < 0:
+			return fmt.Errorf("%s variant %s has negative stock %d", m.Name, variant.VariantOptions, *variant.Stock)
+		}
+		seen[variant.VariantOptions] = true
+	}
+	return nil
+}
+
+// PriceOf returns price of variant, or of merch itself when variant is nil...
+func (m *Merch) PriceOf(variant *MerchVariant) (Coins, error) {
+	if variant == nil {
+		return m.Price, nil
+	}
+	return m.Price.Add(variant.PriceDelta)
+}
+
+// Variant finds variant with given options. Without options it returns nil for merch without variants
+// and the first variant otherwise, so clients unaware of variants still buy item the store can hand over...
+func (m *Merch) Variant(options VariantOptions) (*MerchVariant, error) {
+	if options == (VariantOptions{}) {
+		if len(m.Variants) == 0 {
+			return nil, nil
+		}
+		return &m.Variants[0], nil
+	}
+	for i := range m.Variants {
+		if m.Variants[i].VariantOptions == options {
+			return &m.Variants[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s: %w", m.Name, options, ErrVariantNotFound)
+}
+
+// VariantOptions tell variants of one merch item apart, empty option means variants don't differ in it...
+type VariantOptions struct {
+	Size  string `json:"size,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+// String describes options for humans...
+func (o VariantOptions) String() string {
+	switch {
+	case o.Size != "" && o.Color != "":
+		return o.Size + ", " + o.Color
+	case o.Size != "" || o.Color != "":
+		return o.Size + o.Color
+	default:
+		return "without options"
+	}
+}
+
+// MerchVariant is variant of merch item with its own stock, it costs merch price plus PriceDelta...
+type MerchVariant struct {
+	ID int `json:"id"`
+	VariantOptions
+	PriceDelta Coins `json:"priceDelta,omitempty"`
+	Stock      *int  `json:"stock,omitempty"`
+}
+
+// InStock reports whether at least one item of variant can be bought...
+func (v *MerchVariant) InStock() bool {
+	return v.Stock == nil || *v.Stock > 0
+}
+
+// PurchaseLimit caps number of items one user can buy in total and within rolling window of WindowDays,
+// zero fields mean there is no such cap...
+type PurchaseLimit struct {
+	Lifetime   int `json:"lifetime,omitempty"`
+	PerWindow  int `json:"perWindow,omitempty"`
+	WindowDays int `json:"windowDays,omitempty"`
+}
+
+// NewPurchaseLimit returns limit with given caps or nil if there are none...
+func NewPurchaseLimit(lifetime, perWindow, windowDays int) *PurchaseLimit {
+	if lifetime == 0 && perWindow == 0 && windowDays == 0 {
+		return nil
+	}
+	return &PurchaseLimit{Lifetime: lifetime, PerWindow: perWindow, WindowDays: windowDays}
+}
+
+// Validate checks that caps are not negative and window has both size and cap...
+func (l *PurchaseLimit) Validate() error {
+	if l == nil {
+		return nil
+	}
+	if l.Lifetime < 0 || l.PerWindow < 0 || l.WindowDays < 0 {
+		return fmt.Errorf("purchase limit %+v has negative values", *l)
+	}
+	if (l.PerWindow == 0) != (l.WindowDays == 0) {
+		return fmt.Errorf("purchase limit %+v must set both perWindow and windowDays", *l)
+	}
+	return nil
+}
+
+// Window returns duration of rolling window...
+func (l *PurchaseLimit) Window() time.Duration {
+	return time.Duration(l.WindowDays) * 24 * time.Hour
+}
+
+// Check returns ErrLimitExceeded if user who bought total items, inWindow of them within window, can't buy one more...
+func (l *PurchaseLimit) Check(total, inWindow int) error {
+	if l == nil {
+		return nil
+	}
+	if l.Lifetime > 0 && total >= l.Lifetime {
+		return fmt.Errorf("%w: %d items per user", ErrLimitExceeded, l.Lifetime)
+	}
+	if l.PerWindow > 0 && inWindow >= l.PerWindow {
+		return fmt.Errorf("%w: %d items per %d days", ErrLimitExceeded, l.PerWindow, l.WindowDays)
+	}
+	return nil
+}
+
+// String describes limit for humans...
+func (l *PurchaseLimit) String() string {
+	switch {
+	case l == nil:
+		return "no limit"
+	case l.Lifetime > 0 && l.PerWindow > 0:
+		return fmt.Sprintf("%d per %d days, %d total", l.PerWindow, l.WindowDays, l.Lifetime)
+	case l.PerWindow > 0:
+		return fmt.Sprintf("%d per %d days", l.PerWindow, l.WindowDays)
+	default:
+		return fmt.Sprintf("%d total", l.Lifetime)
+	}
+}
+
+// CatalogItem is merch item that we send inside catalog response...
+type CatalogItem struct {
+	Name     string           `json:"name"`
+	Price    Coins            `json:"price"`
+	Stock    *int             `json:"stock,omitempty"`
+	Limit    *PurchaseLimit   `json:"limit,omitempty"`
+	Variants []CatalogVariant `json:"variants,omitempty"`
+}
+
+// CatalogVariant is variant of merch item that we send inside catalog response...
+type CatalogVariant struct {
+	VariantOptions
+	Price Coins `json:"price"`
+	Stock *int  `json:"stock,omitempty"`
+}
+
+// CatalogResponse lists merch available in store...
+type CatalogResponse struct {
+	Items []CatalogItem `json:"items"`
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPurchaseLimit(t *testing.T) {
+	assert.Nil(t, NewPurchaseLimit(0, 0, 0))
+	assert.Equal(t, &PurchaseLimit{PerWindow: 1, WindowDays: 91}, NewPurchaseLimit(0, 1, 91))
+}
+
+func TestPurchaseLimit_Validate(t *testing.T) {
+	var none *PurchaseLimit
+	assert.NoError(t, none.Validate())
+	assert.NoError(t, (&PurchaseLimit{Lifetime: 3}).Validate())
+	assert.NoError(t, (&PurchaseLimit{PerWindow: 1, WindowDays: 91}).Validate())
+
+	assert.Error(t, (&PurchaseLimit{Lifetime: -1}).Validate())
+	assert.Error(t, (&PurchaseLimit{PerWindow: 1}).Validate())
+	assert.Error(t, (&PurchaseLimit{WindowDays: 91}).Validate())
+}
+
+func TestPurchaseLimit_Check(t *testing.T) {
+	var none *PurchaseLimit
+	assert.NoError(t, none.Check(100, 100))
+
+	limit := &PurchaseLimit{Lifetime: 3, PerWindow: 1, WindowDays: 91}
+	assert.Equal(t, 91*24*time.Hour, limit.Window())
+	assert.NoError(t, limit.Check(0, 0))
+	assert.NoError(t, limit.Check(2, 0))
+	assert.ErrorIs(t, limit.Check(2, 1), ErrLimitExceeded)
+	assert.ErrorIs(t, limit.Check(3, 0), ErrLimitExceeded)
+}
+
+func TestPurchaseLimit_String(t *testing.T) {
+	var none *PurchaseLimit
+	assert.Equal(t, "no limit", none.String())
+	assert.Equal(t, "1 per 91 days", NewPurchaseLimit(0, 1, 91).String())
+	assert.Equal(t, "2 total", NewPurchaseLimit(2, 0, 0).String())
+	assert.Equal(t, "1 per 91 days, 2 total", NewPurchaseLimit(2, 1, 91).String())
+}
+
+func TestMerch_Variant(t *testing.T) {
+	cup := Merch{Name: "cup", Price: 20}
+	variant, err := cup.Variant(VariantOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, variant)
+	_, err = cup.Variant(VariantOptions{Size: "M"})
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+
+	shirt := Merch{Name: "t-shirt", Price: 80, Variants: []MerchVariant{
+		{ID: 1, VariantOptions: VariantOptions{Size: "S"}},
+		{ID: 2, VariantOptions: VariantOptions{Size: "XL"}},
+	}}
+	variant, err = shirt.Variant(VariantOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, variant.ID)
+	variant, err = shirt.Variant(VariantOptions{Size: "XL"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, variant.ID)
+	_, err = shirt.Variant(VariantOptions{Size: "XXS"})
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStatusTransition is returned when order can't move from its current status to requested one...
+var ErrStatusTransition = errors.New("order status can't be changed")
+
+// ErrRefundExpired is returned when user cancels order placed longer ago than refund window...
+var ErrRefundExpired = errors.New("refund window has passed")
+
+// OrderStatus is stage of order fulfilment...
+type OrderStatus string
+
+// Order statuses, order is placed by purchase, packed and handed over by store manager or cancelled before that,
+// cancelled order is always refunded.
+const (
+	OrderPlaced     OrderStatus = "placed"
+	OrderPacked     OrderStatus = "packed"
+	OrderHandedOver OrderStatus = "handed_over"
+	OrderCancelled  OrderStatus = "cancelled"
+)
+
+// orderTransitions lists statuses order may move to from each status, handed over and cancelled orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPlaced: {OrderPacked, OrderCancelled},
+	OrderPacked: {OrderHandedOver, OrderCancelled},
+}
+
+// Valid reports whether s is one of known statuses...
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderPlaced, OrderPacked, OrderHandedOver, OrderCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// CheckTransition returns ErrStatusTransition unless order in status s may move to next...
+func (s OrderStatus) CheckTransition(next OrderStatus) error {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w from %s to %s", ErrStatusTransition, s, next)
+}
+
+// Order is physical item user has to receive for purchase, its ID is ID of purchase,
+// gift is handed over to Recipient instead of user who bought it...
+type Order struct {
+	ID        int         `json:"id"`
+	Username  string      `json:"username"`
+	Recipient string      `json:"recipient,omitempty"`
+	Item      string      `json:"item"`
+	Size      string      `json:"size,omitempty"`
+	Color     string      `json:"color,omitempty"`
+	Status    OrderStatus `json:"status"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// RefundRules restrict which orders may be refunded, zero value allows refund of any order
+// that is not handed over or cancelled yet...
+type RefundRules struct {
+	// UserID is owner order must belong to, zero means any user.
+	UserID int
+	// Window is how long ago order may be placed, zero means any time.
+	Window time.Duration
+	// HandedOver allows refund of order that is already handed over, item is expected to be returned to office.
+	HandedOver bool
+}
+
+// CheckRefund returns error unless order in status s placed age ago may be refunded under rules...
+func (r RefundRules) CheckRefund(s OrderStatus, age time.Duration) error {
+	if r.Window > 0 && age > r.Window {
+		return fmt.Errorf("%w: order was placed %s ago", ErrRefundExpired, age.Round(time.Minute))
+	}
+	if r.HandedOver && s == OrderHandedOver {
+		return nil
+	}
+	return s.CheckTransition(OrderCancelled)
+}
+
+// OrdersResponse - Response of /api/orders...
+type OrdersResponse struct {
+	Orders []Order `json:"orders"`
+}
+
+// OrderStatusRequest - Request of /api/orders/{id}/status...
+type OrderStatusRequest struct {
+	Status OrderStatus `json:"status"`
+}
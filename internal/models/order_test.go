@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderStatus_CheckTransition(t *testing.T) {
+	assert.NoError(t, OrderPlaced.CheckTransition(OrderPacked))
+	assert.NoError(t, OrderPlaced.CheckTransition(OrderCancelled))
+	assert.NoError(t, OrderPacked.CheckTransition(OrderHandedOver))
+	assert.NoError(t, OrderPacked.CheckTransition(OrderCancelled))
+
+	assert.ErrorIs(t, OrderPlaced.CheckTransition(OrderHandedOver), ErrStatusTransition)
+	assert.ErrorIs(t, OrderPacked.CheckTransition(OrderPlaced), ErrStatusTransition)
+	assert.ErrorIs(t, OrderPacked.CheckTransition(OrderPacked), ErrStatusTransition)
+	assert.ErrorIs(t, OrderHandedOver.CheckTransition(OrderCancelled), ErrStatusTransition)
+	assert.ErrorIs(t, OrderCancelled.CheckTransition(OrderPlaced), ErrStatusTransition)
+}
+
+func TestOrderStatusRequest_Validate(t *testing.T) {
+	assert.NoError(t, OrderStatusRequest{Status: OrderHandedOver}.Validate())
+	assert.Error(t, OrderStatusRequest{Status: "lost"}.Validate())
+	assert.Error(t, OrderStatusRequest{}.Validate())
+}
+
+func TestRefundRules_CheckRefund(t *testing.T) {
+	placed := time.Minute
+
+	var anyOrder RefundRules
+	assert.NoError(t, anyOrder.CheckRefund(OrderPlaced, placed))
+	assert.NoError(t, anyOrder.CheckRefund(OrderPacked, placed))
+	assert.ErrorIs(t, anyOrder.CheckRefund(OrderHandedOver, placed), ErrStatusTransition)
+	assert.ErrorIs(t, anyOrder.CheckRefund(OrderCancelled, placed), ErrStatusTransition)
+
+	returned := RefundRules{HandedOver: true}
+	assert.NoError(t, returned.CheckRefund(OrderHandedOver, placed))
+	assert.ErrorIs(t, returned.CheckRefund(OrderCancelled, placed), ErrStatusTransition)
+
+	recent := RefundRules{Window: time.Hour}
+	assert.NoError(t, recent.CheckRefund(OrderPlaced, placed))
+	assert.ErrorIs(t, recent.CheckRefund(OrderPlaced, 2*time.Hour), ErrRefundExpired)
+}
@@ -5,12 +5,40 @@ type Transaction struct {
 	ID         int    `json:"id"`
 	FromUserID int    `json:"from_user_id"`
 	ToUserID   int    `json:"to_user_id"`
-	Amount     int    `json:"amount"`
+	Amount     Coins  `json:"amount"`
 	CreatedAt  string `json:"created_at"`
+	ReversalOf int    `json:"reversal_of,omitempty"`
 }
 
-// TransactionInfo contains transaction information that we send inside response to user...
-type TransactionInfo struct {
+// RefundInfo contains information about coins returned for cancelled order that we send inside response to user...
+type RefundInfo struct {
+	OrderID int    `json:"orderId"`
+	Item    string `json:"item"`
+	Size    string `json:"size,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Amount  Coins  `json:"amount"`
+	Reason  string `json:"reason"`
+}
+
+// GiftInfo contains information about merch one user bought for another that we send inside response to user,
+// Username is recipient of sent gift and buyer of received one, price is shown only to buyer,
+// refunded gifts stay in history of both users with Refunded set...
+type GiftInfo struct {
+	OrderID  int    `json:"orderId"`
 	Username string `json:"username"`
-	Amount   int    `json:"amount"`
+	Item     string `json:"item"`
+	Size     string `json:"size,omitempty"`
+	Color    string `json:"color,omitempty"`
+	Amount   Coins  `json:"amount,omitempty"`
+	Refunded bool   `json:"refunded,omitempty"`
+}
+
+// TransactionInfo contains transaction information that we send inside response to user,
+// transfer reversed by admin and its reversal refer to each other...
+type TransactionInfo struct {
+	ID         int    `json:"id"`
+	Username   string `json:"username"`
+	Amount     Coins  `json:"amount"`
+	ReversalOf int    `json:"reversalOf,omitempty"`
+	ReversedBy int    `json:"reversedBy,omitempty"`
 }
@@ -1,9 +1,27 @@
 package models
 
+// Role decides what user is allowed to do besides buying merch and sending coins...
+type Role string
+
+// Roles of users, every user is an employee until admin makes them a store manager.
+const (
+	RoleEmployee Role = "employee"
+	RoleManager  Role = "manager"
+)
+
+// Valid reports whether r is one of known roles...
+func (r Role) Valid() bool {
+	return r == RoleEmployee || r == RoleManager
+}
+
+// DefaultCoins is balance every new user starts with...
+const DefaultCoins Coins = 1000
+
 // User contains information about user of our store...
 type User struct {
 	ID           int    `json:"id"`
 	Username     string `json:"username"`
 	PasswordHash string `json:"-"`
-	Coins        int    `json:"coins"`
+	Coins        Coins  `json:"coins"`
+	Role         Role   `json:"role"`
 }
@@ -0,0 +1,57 @@
+package models
+
+import "merch_store/internal/validation"
+
+const (
+	// MaxUsernameLength matches size of users.username column.
+	MaxUsernameLength = 255
+	// MaxPasswordBytes is the longest password bcrypt can hash.
+	MaxPasswordBytes = 72
+)
+
+// ErrorResponse - Response of any request that failed...
+type ErrorResponse struct {
+	Code   ErrorCode               `json:"code,omitempty"`
+	Errors []validation.FieldError `json:"errors"`
+}
+
+// ErrorCode tells clients why request failed without parsing messages, it's empty for errors
+// that are told apart by status code alone...
+type ErrorCode string
+
+// Error codes of requests that failed because of business rules.
+const (
+	CodeInsufficientFunds ErrorCode = "insufficient_funds"
+	CodeOutOfStock        ErrorCode = "out_of_stock"
+	CodeLimitExceeded     ErrorCode = "limit_exceeded"
+	CodeVariantNotFound   ErrorCode = "variant_not_found"
+	CodeStatusTransition  ErrorCode = "status_transition"
+	CodeRefundExpired     ErrorCode = "refund_expired"
+	CodeCoinsOverflow     ErrorCode = "coins_overflow"
+)
+
+// Validate checks fields of AuthRequest...
+func (r AuthRequest) Validate() error {
+	var v validation.Validator
+	v.Required("username", r.Username)
+	v.MaxLength("username", r.Username, MaxUsernameLength)
+	v.Required("password", r.Password)
+	v.MaxBytes("password", r.Password, MaxPasswordBytes)
+	return v.Err()
+}
+
+// Validate checks fields of SendCoinRequest...
+func (r SendCoinRequest) Validate() error {
+	var v validation.Validator
+	v.Required("toUser", r.ToUser)
+	v.MaxLength("toUser", r.ToUser, MaxUsernameLength)
+	v.Positive("amount", int64(r.Amount))
+	return v.Err()
+}
+
+// Validate checks fields of OrderStatusRequest...
+func (r OrderStatusRequest) Validate() error {
+	var v validation.Validator
+	v.Check(r.Status.Valid(), "status", "must be one of placed, packed, handed_over, cancelled")
+	return v.Err()
+}
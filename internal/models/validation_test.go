@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"merch_store/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthRequest_Validate(t *testing.T) {
+	assert.NoError(t, AuthRequest{Username: "alice", Password: "secret"}.Validate())
+
+	errs, ok := validation.AsErrors(AuthRequest{Username: " ", Password: strings.Repeat("p", MaxPasswordBytes+1)}.Validate())
+	assert.True(t, ok)
+	assert.Equal(t, validation.Errors{
+		{Field: "username", Message: "is required"},
+		{Field: "password", Message: "must be at most 72 bytes long"},
+	}, errs)
+}
+
+func TestSendCoinRequest_Validate(t *testing.T) {
+	assert.NoError(t, SendCoinRequest{ToUser: "bob", Amount: 1}.Validate())
+
+	for _, amount := range []Coins{0, -5} {
+		errs, ok := validation.AsErrors(SendCoinRequest{ToUser: "bob", Amount: amount}.Validate())
+		assert.True(t, ok)
+		assert.Equal(t, validation.Errors{{Field: "amount", Message: "must be positive"}}, errs)
+	}
+
+	errs, ok := validation.AsErrors(SendCoinRequest{Amount: 10}.Validate())
+	assert.True(t, ok)
+	assert.Equal(t, "toUser", errs[0].Field)
+}
@@ -0,0 +1,71 @@
+// Package openapi serves OpenAPI specification of the API and validates requests and responses against it.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// SpecPath and DocsPath are routes where specification and Swagger UI are served.
+const (
+	SpecPath = "/api/openapi.json"
+	DocsPath = "/api/docs"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns OpenAPI document in JSON...
+func Spec() []byte {
+	return spec
+}
+
+// Load parses and validates OpenAPI document...
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+// SpecHandler serves OpenAPI document...
+func SpecHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	})
+}
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Merch Store API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: {{.}}, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`))
+
+// DocsHandler serves Swagger UI page for OpenAPI document available at specURL...
+func DocsHandler(specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = docsPage.Execute(w, specURL)
+	})
+}
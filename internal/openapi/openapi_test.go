@@ -0,0 +1,204 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"merch_store/internal/auth"
+	"merch_store/internal/db"
+	"merch_store/internal/handlers"
+	"merch_store/internal/models"
+	"merch_store/internal/openapi"
+	"merch_store/internal/seed"
+)
+
+func newRouter(t *testing.T, mode openapi.Mode) (*mux.Router, *db.MemoryDatabase) {
+	t.Helper()
+
+	memDB := db.NewMemoryDatabase()
+	_, err := seed.Load(context.Background(), memDB, "", false)
+	require.NoError(t, err)
+
+	validator, err := openapi.NewValidator(mode)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(validator.Middleware)
+	router.Handle(openapi.SpecPath, openapi.SpecHandler())
+	handlers.NewHandler(memDB).RegisterRoutes(router)
+	return router, memDB
+}
+
+func token(t *testing.T, username string) string {
+	t.Helper()
+
+	token, err := auth.GenerateToken(username)
+	require.NoError(t, err)
+	return token
+}
+
+func TestLoad(t *testing.T) {
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	handlers.NewHandler(db.NewMemoryDatabase()).RegisterRoutes(router)
+
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		assert.NotNil(t, doc.Paths.Find(path), "route %s is missing in OpenAPI document", path)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestSpecHandler(t *testing.T) {
+	router, _ := newRouter(t, openapi.ValidateOff)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, openapi.SpecPath, nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var doc openapi3.T
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+}
+
+// TestHandlersConformToSpec sends requests for every operation through validator in ValidateAll mode,
+// which replaces response that doesn't match specification with 500...
+func TestHandlersConformToSpec(t *testing.T) {
+	router, memDB := newRouter(t, openapi.ValidateAll)
+	memDB.PutUser("receiver", "hash", 0)
+	memDB.PutUser("rich", "hash", math.MaxInt64-10)
+	memDB.PutUser("manager", "hash", 0)
+	_, err := memDB.SetUserRole(context.Background(), "manager", models.RoleManager)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		user   string
+		status int
+	}{
+		{name: "healthz", method: http.MethodGet, path: "/healthz", status: http.StatusOK},
+		{name: "readyz", method: http.MethodGet, path: "/readyz", status: http.StatusOK},
+		{name: "register", method: http.MethodPost, path: "/api/auth", body: `{"username": "alice", "password": "secret"}`, status: http.StatusOK},
+		{name: "login", method: http.MethodPost, path: "/api/auth", body: `{"username": "alice", "password": "secret"}`, status: http.StatusOK},
+		{name: "wrong password", method: http.MethodPost, path: "/api/auth", body: `{"username": "alice", "password": "wrong"}`, status: http.StatusUnauthorized},
+		{name: "empty info", method: http.MethodGet, path: "/api/info", user: "alice", status: http.StatusOK},
+		{name: "info without token", method: http.MethodGet, path: "/api/info", status: http.StatusUnauthorized},
+		{name: "info of unknown user", method: http.MethodGet, path: "/api/info", user: "ghost", status: http.StatusNotFound},
+		{name: "send", method: http.MethodPost, path: "/api/sendCoin", body: `{"toUser": "receiver", "amount": 100}`, user: "alice", status: http.StatusOK},
+		{name: "send to unknown", method: http.MethodPost, path: "/api/sendCoin", body: `{"toUser": "ghost", "amount": 1}`, user: "alice", status: http.StatusNotFound},
+		{name: "send too much", method: http.MethodPost, path: "/api/sendCoin", body: `{"toUser": "receiver", "amount": 100000}`, user: "alice", status: http.StatusBadRequest},
+		{name: "send overflow", method: http.MethodPost, path: "/api/sendCoin", body: `{"toUser": "rich", "amount": 100}`, user: "alice", status: http.StatusBadRequest},
+		{name: "send to self", method: http.MethodPost, path: "/api/sendCoin", body: `{"toUser": "alice", "amount": 1}`, user: "alice", status: http.StatusBadRequest},
+		{name: "buy", method: http.MethodGet, path: "/api/buy/cup", user: "alice", status: http.StatusOK},
+		{name: "buy with post", method: http.MethodPost, path: "/api/buy/pen", user: "alice", status: http.StatusOK},
+		{name: "buy variant", method: http.MethodPost, path: "/api/buy/t-shirt?size=M", user: "alice", status: http.StatusOK},
+		{name: "buy unknown variant", method: http.MethodPost, path: "/api/buy/t-shirt?size=XXS", user: "alice", status: http.StatusNotFound},
+		{name: "gift", method: http.MethodPost, path: "/api/buy/pen?to=receiver", user: "alice", status: http.StatusOK},
+		{name: "gift to self", method: http.MethodPost, path: "/api/buy/pen?to=alice", user: "alice", status: http.StatusBadRequest},
+		{name: "gift to unknown", method: http.MethodPost, path: "/api/buy/pen?to=ghost", user: "alice", status: http.StatusNotFound},
+		{name: "buy unknown item", method: http.MethodGet, path: "/api/buy/yacht", user: "alice", status: http.StatusNotFound},
+		{name: "buy too expensive", method: http.MethodGet, path: "/api/buy/pink-hoody", user: "receiver", status: http.StatusBadRequest},
+		{name: "catalog", method: http.MethodGet, path: "/api/merch", user: "alice", status: http.StatusOK},
+		{name: "catalog without token", method: http.MethodGet, path: "/api/merch", status: http.StatusUnauthorized},
+		{name: "full info", method: http.MethodGet, path: "/api/info", user: "alice", status: http.StatusOK},
+		{name: "receiver info", method: http.MethodGet, path: "/api/info", user: "receiver", status: http.StatusOK},
+		{name: "orders", method: http.MethodGet, path: "/api/orders", user: "manager", status: http.StatusOK},
+		{name: "orders by status", method: http.MethodGet, path: "/api/orders?status=placed", user: "manager", status: http.StatusOK},
+		{name: "orders for employee", method: http.MethodGet, path: "/api/orders", user: "alice", status: http.StatusForbidden},
+		{name: "pack order", method: http.MethodPost, path: "/api/orders/1/status", body: `{"status": "packed"}`, user: "manager", status: http.StatusOK},
+		{name: "hand over placed order", method: http.MethodPost, path: "/api/orders/2/status", body: `{"status": "handed_over"}`, user: "manager", status: http.StatusBadRequest},
+		{name: "pack unknown order", method: http.MethodPost, path: "/api/orders/100/status", body: `{"status": "packed"}`, user: "manager", status: http.StatusNotFound},
+		{name: "pack order as employee", method: http.MethodPost, path: "/api/orders/2/status", body: `{"status": "packed"}`, user: "alice", status: http.StatusForbidden},
+		{name: "cancel order", method: http.MethodPost, path: "/api/orders/2/cancel", user: "alice", status: http.StatusOK},
+		{name: "cancel cancelled order", method: http.MethodPost, path: "/api/orders/2/cancel", user: "alice", status: http.StatusBadRequest},
+		{name: "cancel order of other user", method: http.MethodPost, path: "/api/orders/1/cancel", user: "receiver", status: http.StatusNotFound},
+		{name: "info with orders", method: http.MethodGet, path: "/api/info", user: "alice", status: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tc.user != "" {
+				req.Header.Set("Authorization", token(t, tc.user))
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestValidator_RejectsInvalidRequests(t *testing.T) {
+	router, _ := newRouter(t, openapi.ValidateRequests)
+
+	cases := map[string]struct {
+		path  string
+		body  string
+		field string
+	}{
+		"missing password":   {path: "/api/auth", body: `{"username": "alice"}`, field: "password"},
+		"amount is string":   {path: "/api/sendCoin", body: `{"toUser": "bob", "amount": "10"}`, field: "amount"},
+		"amount is negative": {path: "/api/sendCoin", body: `{"toUser": "bob", "amount": -10}`, field: "amount"},
+		"unknown status":     {path: "/api/orders/1/status", body: `{"status": "lost"}`, field: "status"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", token(t, "alice"))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			require.NotEmpty(t, response.Errors)
+			assert.Equal(t, tc.field, response.Errors[0].Field)
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for value, expected := range map[string]openapi.Mode{
+		"":         openapi.ValidateOff,
+		"off":      openapi.ValidateOff,
+		"requests": openapi.ValidateRequests,
+		"all":      openapi.ValidateAll,
+	} {
+		mode, err := openapi.ParseMode(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, mode)
+	}
+
+	_, err := openapi.ParseMode("strict")
+	assert.Error(t, err)
+}
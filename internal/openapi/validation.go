@@ -0,0 +1,185 @@
+package openapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"merch_store/internal/httperror"
+	"merch_store/internal/logging"
+	"merch_store/internal/validation"
+)
+
+// ValidationEnv is environment variable with validation mode, see ParseMode.
+const ValidationEnv = "OPENAPI_VALIDATION"
+
+// Mode tells which messages are checked against specification...
+type Mode int
+
+// Validation modes, in ValidateAll mode response that doesn't match specification is replaced with 500.
+const (
+	ValidateOff Mode = iota
+	ValidateRequests
+	ValidateAll
+)
+
+// ParseMode parses "off" (or empty string), "requests" or "all"...
+func ParseMode(value string) (Mode, error) {
+	switch value {
+	case "", "off":
+		return ValidateOff, nil
+	case "requests":
+		return ValidateRequests, nil
+	case "all":
+		return ValidateAll, nil
+	default:
+		return ValidateOff, fmt.Errorf("unknown %s value %q, expected off, requests or all", ValidationEnv, value)
+	}
+}
+
+// Validator checks requests and responses of routes described in specification, other routes are passed as is...
+type Validator struct {
+	router routers.Router
+	mode   Mode
+}
+
+// NewValidator creates validator working in mode...
+func NewValidator(mode Mode) (*Validator, error) {
+	doc, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// servers are ignored so that validation doesn't depend on host server is reached by
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{router: router, mode: mode}, nil
+}
+
+var filterOptions = &openapi3filter.Options{
+	MultiError: true,
+	// tokens are checked by handlers, so that bad token gives the same 401 with or without validation
+	AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+}
+
+// Middleware validates requests and, in ValidateAll mode, responses...
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	if v.mode == ValidateOff {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestInput := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    filterOptions,
+		}
+		if err = openapi3filter.ValidateRequest(r.Context(), requestInput); err != nil {
+			httperror.Write(w, http.StatusBadRequest, "", requestErrors(err))
+			return
+		}
+
+		if v.mode != ValidateAll {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: requestInput,
+			Status:                 recorder.status,
+			Header:                 recorder.header,
+			Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			Options:                filterOptions,
+		})
+		if err != nil {
+			logging.FromContext(r.Context()).Error("response doesn't match API specification",
+				slog.String("operation", route.Operation.OperationID), slog.Int("status", recorder.status), slog.Any("error", err))
+			httperror.WriteMessage(w, http.StatusInternalServerError, "Response doesn't match API specification")
+			return
+		}
+
+		recorder.flush(w)
+	})
+}
+
+// requestErrors converts validation error to field errors, body fields are named by their JSON path...
+func requestErrors(err error) []validation.FieldError {
+	var multi openapi3.MultiError
+	if !errors.As(err, &multi) {
+		multi = openapi3.MultiError{err}
+	}
+
+	fieldErrors := make([]validation.FieldError, 0, len(multi))
+	for _, err := range multi {
+		fieldError := validation.FieldError{Message: err.Error()}
+
+		var requestErr *openapi3filter.RequestError
+		if errors.As(err, &requestErr) {
+			fieldError.Message = requestErr.Reason
+			if requestErr.Parameter != nil {
+				fieldError.Field = requestErr.Parameter.Name
+			}
+		}
+
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			fieldError.Field = strings.Join(schemaErr.JSONPointer(), ".")
+			fieldError.Message = schemaErr.Reason
+		}
+
+		if fieldError.Message == "" {
+			fieldError.Message = err.Error()
+		}
+		fieldErrors = append(fieldErrors, fieldError)
+	}
+	return fieldErrors
+}
+
+// responseRecorder keeps response in memory until it is validated...
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) flush(w http.ResponseWriter) {
+	for key, values := range r.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(r.status)
+	_, _ = w.Write(r.body.Bytes())
+}
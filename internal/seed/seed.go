@@ -0,0 +1,93 @@
+// Package seed - package for loading merch catalog into the store database.
+package seed
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"merch_store/internal/models"
+)
+
+//go:embed merch.json
+var defaultCatalog []byte
+
+// MerchStore stores merch catalog...
+type MerchStore interface {
+	InsertMerch(ctx context.Context, items []models.Merch) error
+	UpsertMerch(ctx context.Context, items []models.Merch) error
+}
+
+// DefaultCatalog returns catalog shipped with the server...
+func DefaultCatalog() ([]models.Merch, error) {
+	return ReadCatalog(bytes.NewReader(defaultCatalog))
+}
+
+// ReadCatalogFile reads catalog from JSON file at path...
+func ReadCatalogFile(path string) ([]models.Merch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return ReadCatalog(f)
+}
+
+// ReadCatalog reads catalog in JSON format and validates it...
+func ReadCatalog(r io.Reader) ([]models.Merch, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var items []models.Merch
+	if err := decoder.Decode(&items); err != nil {
+		return nil, fmt.Errorf("decode catalog: %w", err)
+	}
+
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		if item.Name == "" {
+			return nil, fmt.Errorf("catalog item %d has empty name", i)
+		}
+		if err := item.Validate(); err != nil {
+			return nil, fmt.Errorf("catalog item: %w", err)
+		}
+		if seen[item.Name] {
+			return nil, fmt.Errorf("catalog item %q is duplicated", item.Name)
+		}
+		seen[item.Name] = true
+	}
+
+	return items, nil
+}
+
+// Load reads catalog from path, or default catalog if path is empty, and adds missing items to store.
+// Existing items keep their price, limit and price deltas unless update is set...
+func Load(ctx context.Context, store MerchStore, path string, update bool) ([]models.Merch, error) {
+	var items []models.Merch
+	var err error
+	if path == "" {
+		items, err = DefaultCatalog()
+	} else {
+		items, err = ReadCatalogFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if update {
+		err = store.UpsertMerch(ctx, items)
+	} else {
+		err = store.InsertMerch(ctx, items)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
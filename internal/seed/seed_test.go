@@ -0,0 +1,90 @@
+package seed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merch_store/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingStore struct {
+	inserted []models.Merch
+	upserted []models.Merch
+}
+
+func (rs *recordingStore) InsertMerch(_ context.Context, items []models.Merch) error {
+	rs.inserted = append(rs.inserted, items...)
+	return nil
+}
+
+func (rs *recordingStore) UpsertMerch(_ context.Context, items []models.Merch) error {
+	rs.upserted = append(rs.upserted, items...)
+	return nil
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	items, err := DefaultCatalog()
+	assert.NoError(t, err)
+	assert.Len(t, items, 10)
+	assert.Equal(t, "t-shirt", items[0].Name)
+	assert.Equal(t, models.Coins(80), items[0].Price)
+	assert.Nil(t, items[0].Stock)
+	if assert.NotNil(t, items[9].Stock) {
+		assert.Equal(t, 50, *items[9].Stock)
+	}
+	assert.Equal(t, models.NewPurchaseLimit(0, 1, 91), items[9].Limit)
+	assert.Len(t, items[0].Variants, 4)
+	price, err := items[0].PriceOf(&items[0].Variants[3])
+	assert.NoError(t, err)
+	assert.Equal(t, models.Coins(90), price)
+}
+
+func TestReadCatalog_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"empty name":     `[{"name": "", "price": 10}]`,
+		"zero price":     `[{"name": "pen", "price": 0}]`,
+		"negative stock": `[{"name": "pen", "price": 10, "stock": -1}]`,
+		"limit window":   `[{"name": "pen", "price": 10, "limit": {"perWindow": 1}}]`,
+		"duplicate":      `[{"name": "pen", "price": 10}, {"name": "pen", "price": 20}]`,
+		"empty variant":  `[{"name": "pen", "price": 10, "variants": [{}]}]`,
+		"same variant":   `[{"name": "pen", "price": 10, "variants": [{"color": "red"}, {"color": "red"}]}]`,
+		"variant price":  `[{"name": "pen", "price": 10, "variants": [{"color": "red", "priceDelta": -10}]}]`,
+		"unknown field":  `[{"name": "pen", "price": 10, "colour": "red"}]`,
+		"not an array":   `{"name": "pen", "price": 10}`,
+		"malformed json": `[{"name": "pen"`,
+	}
+
+	for name, catalog := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadCatalog(strings.NewReader(catalog))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_Default(t *testing.T) {
+	store := &recordingStore{}
+	items, err := Load(context.Background(), store, "", false)
+	assert.NoError(t, err)
+	assert.Len(t, items, 10)
+	assert.Equal(t, items, store.inserted)
+	assert.Empty(t, store.upserted)
+}
+
+func TestLoad_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	err := os.WriteFile(path, []byte(`[{"name": "sticker", "price": 5}]`), 0o600)
+	assert.NoError(t, err)
+
+	store := &recordingStore{}
+	items, err := Load(context.Background(), store, path, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Merch{{Name: "sticker", Price: 5}}, items)
+	assert.Equal(t, items, store.upserted)
+	assert.Empty(t, store.inserted)
+}
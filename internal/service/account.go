@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"merch_store/internal/auth"
+	"merch_store/internal/db"
+	"merch_store/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountService registers users and issues tokens...
+type AccountService struct {
+	db db.DB
+}
+
+// NewAccountService generates AccountService...
+func NewAccountService(database db.DB) *AccountService {
+	return &AccountService{db: database}
+}
+
+// Authenticate returns token for user, unknown users are registered automatically with models.DefaultCoins...
+func (s *AccountService) Authenticate(ctx context.Context, username, password string) (string, error) {
+	user, err := s.db.GetUserByUsername(ctx, username)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		user, err = s.register(ctx, username, password)
+		if err != nil {
+			return "", err
+		}
+	case err != nil:
+		return "", err
+	case !auth.CheckPasswordHash(password, user.PasswordHash):
+		return "", ErrInvalidPassword
+	}
+
+	return auth.GenerateToken(user.Username)
+}
+
+func (s *AccountService) register(ctx context.Context, username, password string) (*models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		PasswordHash: string(hashedPassword),
+	}
+	if err = s.db.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
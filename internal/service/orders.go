@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"merch_store/internal/db"
+	"merch_store/internal/metrics"
+	"merch_store/internal/models"
+)
+
+// DefaultRefundWindow is how long after purchase users may cancel their orders themselves.
+const DefaultRefundWindow = 7 * 24 * time.Hour
+
+// OrderService lets store managers track orders placed by purchases and users cancel their orders...
+type OrderService struct {
+	db db.DB
+	// RefundWindow is how long after purchase users may cancel their orders.
+	RefundWindow time.Duration
+}
+
+// NewOrderService generates OrderService...
+func NewOrderService(database db.DB) *OrderService {
+	return &OrderService{db: database, RefundWindow: DefaultRefundWindow}
+}
+
+// manager returns ErrForbidden unless user is store manager...
+func (s *OrderService) manager(ctx context.Context, username string) error {
+	user, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		return notFoundAs(err, ErrUserNotFound)
+	}
+	if user.Role != models.RoleManager {
+		return fmt.Errorf("%s is not store manager: %w", username, ErrForbidden)
+	}
+	return nil
+}
+
+// List returns orders of all users in status, or in any status when it's empty, user must be store manager...
+func (s *OrderService) List(ctx context.Context, username string, status models.OrderStatus) (*models.OrdersResponse, error) {
+	if err := s.manager(ctx, username); err != nil {
+		return nil, err
+	}
+
+	orders, err := s.db.ListOrders(ctx, 0, status)
+	if err != nil {
+		return nil, err
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	return &models.OrdersResponse{Orders: orders}, nil
+}
+
+// SetStatus moves order to next status, user must be store manager...
+func (s *OrderService) SetStatus(ctx context.Context, username string, orderID int, status models.OrderStatus) (*models.Order, error) {
+	if err := s.manager(ctx, username); err != nil {
+		return nil, err
+	}
+
+	order, err := s.db.SetOrderStatus(ctx, orderID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.ObserveOrderStatus(string(order.Status), metrics.ByManager)
+	return order, nil
+}
+
+// Cancel cancels order of user that is not handed over yet and refunds its price, order must be placed within refund window...
+func (s *OrderService) Cancel(ctx context.Context, username string, orderID int) (*models.Order, error) {
+	user, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, notFoundAs(err, ErrUserNotFound)
+	}
+
+	rules := models.RefundRules{UserID: user.ID, Window: s.RefundWindow}
+	order, err := s.db.RefundOrder(ctx, orderID, rules, db.UserCancelReason)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.ObserveOrderStatus(string(order.Status), metrics.ByUser)
+	return order, nil
+}
@@ -0,0 +1,26 @@
+// Package service - package with business rules of the store, it sits between handlers and db.
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"merch_store/internal/db"
+)
+
+// Errors returned by services in addition to db errors, callers should check them with errors.Is.
+var (
+	ErrInvalidPassword   = errors.New("invalid password")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrItemNotFound      = errors.New("item not found")
+	ErrForbidden         = errors.New("forbidden")
+)
+
+// notFoundAs replaces generic db.ErrNotFound with more specific target...
+func notFoundAs(err, target error) error {
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("%w: %w", target, err)
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"merch_store/internal/auth"
+	"merch_store/internal/db"
+	"merch_store/internal/models"
+	"merch_store/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ctx = context.Background()
+
+// fakeDB keeps users and merch in maps, it implements only methods used by services.
+type fakeDB struct {
+	db.DB
+	users     map[string]*models.User
+	merch     map[string]*models.Merch
+	transfers int
+	purchases int
+	gifts     int
+	refunds   []models.RefundRules
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		users: map[string]*models.User{},
+		merch: map[string]*models.Merch{"cup": {ID: 1, Name: "cup", Price: 20}},
+	}
+}
+
+func (f *fakeDB) addUser(username string, coins models.Coins) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	f.users[username] = &models.User{ID: len(f.users) + 1, Username: username, PasswordHash: string(hash), Coins: coins}
+}
+
+func (f *fakeDB) GetUserByUsername(_ context.Context, username string) (*models.User, error) {
+	user, ok := f.users[username]
+	if !ok {
+		return nil, fmt.Errorf("user %s: %w", username, db.ErrNotFound)
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (f *fakeDB) CreateUser(_ context.Context, user *models.User) error {
+	if _, ok := f.users[user.Username]; ok {
+		return db.ErrConflict
+	}
+	f.users[user.Username] = &models.User{ID: len(f.users) + 1, Username: user.Username, PasswordHash: user.PasswordHash, Coins: models.DefaultCoins}
+	return nil
+}
+
+func (f *fakeDB) GetMerchByName(_ context.Context, name string) (*models.Merch, error) {
+	merch, ok := f.merch[name]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return merch, nil
+}
+
+func (f *fakeDB) TransferCoins(_ context.Context, _, _ int, _ models.Coins) error {
+	f.transfers++
+	return nil
+}
+
+func (f *fakeDB) BuyMerch(_ context.Context, _, _, _ int, _ models.Coins) error {
+	f.purchases++
+	return nil
+}
+
+func (f *fakeDB) GiftMerch(_ context.Context, _, _, _, _ int, _ models.Coins) error {
+	f.gifts++
+	return nil
+}
+
+func (f *fakeDB) SetOrderStatus(_ context.Context, orderID int, status models.OrderStatus) (*models.Order, error) {
+	return &models.Order{ID: orderID, Status: status}, nil
+}
+
+func (f *fakeDB) RefundOrder(_ context.Context, orderID int, rules models.RefundRules, reason string) (*models.Order, error) {
+	f.refunds = append(f.refunds, rules)
+	return &models.Order{ID: orderID, Status: models.OrderCancelled}, nil
+}
+
+func TestAccountService_RegistersNewUser(t *testing.T) {
+	fake := newFakeDB()
+	accounts := NewAccountService(fake)
+
+	token, err := accounts.Authenticate(ctx, "alice", "secret")
+	assert.NoError(t, err)
+
+	claims, err := (&auth.DefaultValidator{}).ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, models.DefaultCoins, fake.users["alice"].Coins)
+}
+
+func TestAccountService_WrongPassword(t *testing.T) {
+	fake := newFakeDB()
+	fake.addUser("alice", 100)
+
+	_, err := NewAccountService(fake).Authenticate(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	_, err = NewAccountService(fake).Authenticate(ctx, "alice", "password")
+	assert.NoError(t, err)
+}
+
+func TestWalletService_Send(t *testing.T) {
+	fake := newFakeDB()
+	fake.addUser("alice", 100)
+	fake.addUser("bob", 0)
+	wallet := NewWalletService(fake)
+
+	assert.NoError(t, wallet.Send(ctx, "alice", "bob", 100))
+	assert.Equal(t, 1, fake.transfers)
+}
+
+func TestWalletService_SendRules(t *testing.T) {
+	fake := newFakeDB()
+	fake.addUser("alice", 100)
+	fake.addUser("rich", math.MaxInt64)
+	wallet := NewWalletService(fake)
+
+	err := wallet.Send(ctx, "alice", "alice", 10)
+	_, isValidation := validation.AsErrors(err)
+	assert.True(t, isValidation)
+
+	assert.ErrorIs(t, wallet.Send(ctx, "alice", "nobody", 10), ErrRecipientNotFound)
+	assert.ErrorIs(t, wallet.Send(ctx, "nobody", "alice", 10), ErrUserNotFound)
+	assert.ErrorIs(t, wallet.Send(ctx, "alice", "rich", 101), db.ErrInsufficientFunds)
+	assert.ErrorIs(t, wallet.Send(ctx, "alice", "rich", 1), models.ErrCoinsOverflow)
+	assert.Equal(t, 0, fake.transfers)
+}
+
+func TestStoreService_Buy(t *testing.T) {
+	fake := newFakeDB()
+	fake.addUser("alice", 20)
+	fake.addUser("bob", 19)
+	store := NewStoreService(fake)
+
+	assert.NoError(t, store.Buy(ctx, "alice", "cup", models.VariantOptions{}))
+	assert.ErrorIs(t, store.Buy(ctx, "bob", "cup", models.VariantOptions{}), db.ErrInsufficientFunds)
+	assert.ErrorIs(t, store.Buy(ctx, "alice", "yacht", models.VariantOptions{}), ErrItemNotFound)
+	assert.Equal(t, 1, fake.purchases)
+}
+
+func TestStoreService_Gift(t *testing.T) {
+	fake := newFakeDB()
+	fake.addUser("alice", 20)
+	fake.addUser("bob", 0)
+	store := NewStoreService(fake)
+
+	assert.NoError(t, store.Gift(ctx, "alice", "bob", "cup", models.VariantOptions{}))
+	assert.ErrorIs(t, store.Gift(ctx, "alice", "nobody", "cup", models.VariantOptions{}), ErrRecipientNotFound)
+	assert.ErrorIs(t, store.Gift(ctx, "bob", "alice", "cup", models.VariantOptions{}), db.ErrInsufficientFunds)
+
+	for _, recipient := range []string{"", "alice"} {
+		_, ok := validation.AsErrors(store.Gift(ctx, "alice", recipient, "cup", models.VariantOptions{}))
+		assert.True(t, ok, "recipient %q", recipient)
+	}
+	assert.Equal(t, 1, fake.gifts)
+	assert.Equal(t, 0, fake.purchases)
+}
+
+func TestOrderService_OnlyManagers(t *testing.T) {
+	fake := newFakeDB()
+	fake.addUser("alice", 0)
+	fake.addUser("boss", 0)
+	fake.users["boss"].Role = models.RoleManager
+	orders := NewOrderService(fake)
+
+	_, err := orders.SetStatus(ctx, "alice", 1, models.OrderPacked)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = orders.SetStatus(ctx, "nobody", 1, models.OrderPacked)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	order, err := orders.SetStatus(ctx, "boss", 1, models.OrderPacked)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderPacked, order.Status)
+}
+
+func TestOrderService_Cancel(t *testing.T) {
+	fake := newFakeDB()
+	fake.addUser("alice", 0)
+	orders := NewOrderService(fake)
+
+	order, err := orders.Cancel(ctx, "alice", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderCancelled, order.Status)
+	_, err = orders.Cancel(ctx, "nobody", 1)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// users cancel only their own recent orders that are not handed over
+	assert.Equal(t, []models.RefundRules{{UserID: fake.users["alice"].ID, Window: DefaultRefundWindow}}, fake.refunds)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"merch_store/internal/db"
+	"merch_store/internal/metrics"
+	"merch_store/internal/models"
+	"merch_store/internal/validation"
+)
+
+// StoreService sells merch to users...
+type StoreService struct {
+	db db.DB
+}
+
+// NewStoreService generates StoreService...
+func NewStoreService(database db.DB) *StoreService {
+	return &StoreService{db: database}
+}
+
+// Catalog returns merch available in store sorted by name...
+func (s *StoreService) Catalog(ctx context.Context) (*models.CatalogResponse, error) {
+	catalog, err := s.db.ListMerch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CatalogResponse{Items: make([]models.CatalogItem, 0, len(catalog))}
+	for _, merch := range catalog {
+		item := models.CatalogItem{
+			Name:  merch.Name,
+			Price: merch.Price,
+			Stock: merch.Stock,
+			Limit: merch.Limit,
+		}
+		for _, variant := range merch.Variants {
+			price, err := merch.PriceOf(&variant)
+			if err != nil {
+				return nil, err
+			}
+			item.Variants = append(item.Variants, models.CatalogVariant{
+				VariantOptions: variant.VariantOptions,
+				Price:          price,
+				Stock:          variant.Stock,
+			})
+		}
+		response.Items = append(response.Items, item)
+	}
+	return response, nil
+}
+
+// Buy buys one item for user, options select variant of merch that has them...
+func (s *StoreService) Buy(ctx context.Context, username, item string, options models.VariantOptions) error {
+	return s.buy(ctx, username, "", item, options)
+}
+
+// Gift buys one item for user and puts it to inventory of recipient, options select variant of merch that has them...
+func (s *StoreService) Gift(ctx context.Context, username, recipient, item string, options models.VariantOptions) error {
+	var v validation.Validator
+	v.Required("to", recipient)
+	v.MaxLength("to", recipient, models.MaxUsernameLength)
+	v.Check(recipient != username, "to", "cannot gift merch to yourself")
+	if err := v.Err(); err != nil {
+		return err
+	}
+	return s.buy(ctx, username, recipient, item, options)
+}
+
+// buy buys item for user, recipient gets it when it's not empty...
+func (s *StoreService) buy(ctx context.Context, username, recipient, item string, options models.VariantOptions) error {
+	user, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		return notFoundAs(err, ErrUserNotFound)
+	}
+
+	recipientID := 0
+	if recipient != "" {
+		recipientUser, err := s.db.GetUserByUsername(ctx, recipient)
+		if err != nil {
+			return notFoundAs(err, ErrRecipientNotFound)
+		}
+		recipientID = recipientUser.ID
+	}
+
+	merch, err := s.db.GetMerchByName(ctx, item)
+	if err != nil {
+		return notFoundAs(err, ErrItemNotFound)
+	}
+
+	variant, err := merch.Variant(options)
+	if err != nil {
+		return err
+	}
+	variantID := 0
+	if variant != nil {
+		variantID = variant.ID
+	}
+
+	price, err := merch.PriceOf(variant)
+	if err != nil {
+		return err
+	}
+	if user.Coins < price {
+		metrics.ObserveInsufficientFunds("buy")
+		return db.ErrInsufficientFunds
+	}
+
+	if !merch.InStock() || (variant != nil && !variant.InStock()) {
+		metrics.ObserveOutOfStock(merch.Name)
+		return db.ErrOutOfStock
+	}
+
+	if recipientID != 0 {
+		err = s.db.GiftMerch(ctx, user.ID, recipientID, merch.ID, variantID, price)
+	} else {
+		err = s.db.BuyMerch(ctx, user.ID, merch.ID, variantID, price)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrInsufficientFunds):
+			metrics.ObserveInsufficientFunds("buy")
+		case errors.Is(err, db.ErrOutOfStock):
+			metrics.ObserveOutOfStock(merch.Name)
+		}
+		return err
+	}
+
+	metrics.ObservePurchase(merch.Name)
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"merch_store/internal/db"
+	"merch_store/internal/metrics"
+	"merch_store/internal/models"
+	"merch_store/internal/validation"
+)
+
+// WalletService moves coins between users and reports balances...
+type WalletService struct {
+	db db.DB
+}
+
+// NewWalletService generates WalletService...
+func NewWalletService(database db.DB) *WalletService {
+	return &WalletService{db: database}
+}
+
+// Send transfers amount coins from one user to another...
+func (s *WalletService) Send(ctx context.Context, fromUsername, toUsername string, amount models.Coins) error {
+	if fromUsername == toUsername {
+		return validation.Errors{{Field: "toUser", Message: "cannot send coins to yourself"}}
+	}
+
+	fromUser, err := s.db.GetUserByUsername(ctx, fromUsername)
+	if err != nil {
+		return notFoundAs(err, ErrUserNotFound)
+	}
+
+	toUser, err := s.db.GetUserByUsername(ctx, toUsername)
+	if err != nil {
+		return notFoundAs(err, ErrRecipientNotFound)
+	}
+
+	if fromUser.Coins < amount {
+		metrics.ObserveInsufficientFunds("send_coin")
+		return db.ErrInsufficientFunds
+	}
+
+	if _, err = toUser.Coins.Add(amount); err != nil {
+		return fmt.Errorf("recipient balance: %w", err)
+	}
+
+	err = s.db.TransferCoins(ctx, fromUser.ID, toUser.ID, amount)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			metrics.ObserveInsufficientFunds("send_coin")
+		}
+		return err
+	}
+
+	metrics.ObserveTransfer(int64(amount))
+	return nil
+}
+
+// Info returns balance, inventory, coin history and orders of user...
+func (s *WalletService) Info(ctx context.Context, username string) (*models.InfoResponse, error) {
+	user, err := s.db.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, notFoundAs(err, ErrUserNotFound)
+	}
+
+	inventory, err := s.db.GetUserInventory(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.db.GetUserTransactions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := s.db.ListOrders(ctx, user.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// empty lists are sent as [] rather than null, as API specification requires
+	if inventory == nil {
+		inventory = []models.InventoryInfo{}
+	}
+	if history.Received == nil {
+		history.Received = []models.TransactionInfo{}
+	}
+	if history.Sent == nil {
+		history.Sent = []models.TransactionInfo{}
+	}
+	if history.Refunds == nil {
+		history.Refunds = []models.RefundInfo{}
+	}
+	if history.GiftsSent == nil {
+		history.GiftsSent = []models.GiftInfo{}
+	}
+	if history.GiftsReceived == nil {
+		history.GiftsReceived = []models.GiftInfo{}
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+
+	return &models.InfoResponse{
+		Coins:       user.Coins,
+		Inventory:   inventory,
+		CoinHistory: history,
+		Orders:      orders,
+	}, nil
+}
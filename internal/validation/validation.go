@@ -0,0 +1,80 @@
+// Package validation - package for field-level validation of request models.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes one failed rule...
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors is list of failed rules, it is returned as error by Validate methods...
+type Errors []FieldError
+
+// Error implements error...
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		if fieldError.Field == "" {
+			messages = append(messages, fieldError.Message)
+		} else {
+			messages = append(messages, fieldError.Field+": "+fieldError.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// AsErrors extracts validation errors from err...
+func AsErrors(err error) (Errors, bool) {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	return nil, false
+}
+
+// Validator accumulates failed rules...
+type Validator struct {
+	errs Errors
+}
+
+// Check adds error for field if ok is false...
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.errs = append(v.errs, FieldError{Field: field, Message: message})
+	}
+}
+
+// Required checks that value is not blank...
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// MaxLength checks that value has at most limit characters...
+func (v *Validator) MaxLength(field, value string, limit int) {
+	v.Check(utf8.RuneCountInString(value) <= limit, field, fmt.Sprintf("must be at most %d characters long", limit))
+}
+
+// MaxBytes checks that value has at most limit bytes...
+func (v *Validator) MaxBytes(field, value string, limit int) {
+	v.Check(len(value) <= limit, field, fmt.Sprintf("must be at most %d bytes long", limit))
+}
+
+// Positive checks that value is greater than zero...
+func (v *Validator) Positive(field string, value int64) {
+	v.Check(value > 0, field, "must be positive")
+}
+
+// Err returns accumulated errors or nil if all rules passed...
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
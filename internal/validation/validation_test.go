@@ -0,0 +1,44 @@
+package validation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator_NoErrors(t *testing.T) {
+	var v Validator
+	v.Required("username", "alice")
+	v.MaxLength("username", "alice", 10)
+	v.Positive("amount", 1)
+
+	assert.NoError(t, v.Err())
+}
+
+func TestValidator_CollectsAllErrors(t *testing.T) {
+	var v Validator
+	v.Required("username", "   ")
+	v.MaxBytes("password", "123456", 5)
+	v.Positive("amount", 0)
+
+	errs, ok := AsErrors(v.Err())
+	assert.True(t, ok)
+	assert.Equal(t, Errors{
+		{Field: "username", Message: "is required"},
+		{Field: "password", Message: "must be at most 5 bytes long"},
+		{Field: "amount", Message: "must be positive"},
+	}, errs)
+	assert.Equal(t, "username: is required; password: must be at most 5 bytes long; amount: must be positive", errs.Error())
+}
+
+func TestAsErrors_Wrapped(t *testing.T) {
+	err := fmt.Errorf("decode: %w", Errors{{Message: "broken"}})
+
+	errs, ok := AsErrors(err)
+	assert.True(t, ok)
+	assert.Len(t, errs, 1)
+
+	_, ok = AsErrors(fmt.Errorf("plain error"))
+	assert.False(t, ok)
+}
@@ -25,16 +25,3 @@ CREATE TABLE IF NOT EXISTS transactions (
     amount INTEGER NOT NULL CHECK (amount > 0),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
-
-
-INSERT INTO merch (name, price) VALUES
-('t-shirt', 80),
-('cup', 20),
-('book', 50),
-('pen', 10),
-('powerbank', 200),
-('hoody', 300),
-('umbrella', 200),
-('socks', 10),
-('wallet', 50),
-('pink-hoody', 500);
@@ -0,0 +1,3 @@
+ALTER TABLE transactions ALTER COLUMN amount TYPE INTEGER;
+ALTER TABLE merch ALTER COLUMN price TYPE INTEGER;
+ALTER TABLE users ALTER COLUMN coins TYPE INTEGER;
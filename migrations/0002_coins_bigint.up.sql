@@ -0,0 +1,3 @@
+ALTER TABLE users ALTER COLUMN coins TYPE BIGINT;
+ALTER TABLE merch ALTER COLUMN price TYPE BIGINT;
+ALTER TABLE transactions ALTER COLUMN amount TYPE BIGINT;
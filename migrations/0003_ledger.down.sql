@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS coin_adjustments;
+DROP TABLE IF EXISTS purchases;
@@ -0,0 +1,26 @@
+CREATE TABLE purchases (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    merch_id INTEGER NOT NULL REFERENCES merch(id),
+    price BIGINT NOT NULL CHECK (price > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX purchases_user_id_idx ON purchases (user_id);
+
+CREATE TABLE coin_adjustments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX coin_adjustments_user_id_idx ON coin_adjustments (user_id);
+
+-- purchases made before this migration were not recorded, they are restored from inventory at current prices
+INSERT INTO purchases (user_id, merch_id, price)
+SELECT i.user_id, i.merch_id, m.price
+FROM inventory i
+JOIN merch m ON m.id = i.merch_id
+CROSS JOIN generate_series(1, i.quantity);
@@ -0,0 +1,2 @@
+-- NULL stock means unlimited supply
+ALTER TABLE merch ADD COLUMN stock INTEGER CHECK (stock >= 0);
@@ -0,0 +1,8 @@
+CREATE INDEX purchases_user_id_idx ON purchases (user_id);
+DROP INDEX purchases_user_merch_idx;
+
+ALTER TABLE merch
+    DROP CONSTRAINT merch_window_check,
+    DROP COLUMN lifetime_limit,
+    DROP COLUMN window_limit,
+    DROP COLUMN window_days;
@@ -0,0 +1,10 @@
+-- zero means there is no such limit
+ALTER TABLE merch
+    ADD COLUMN lifetime_limit INTEGER NOT NULL DEFAULT 0 CHECK (lifetime_limit >= 0),
+    ADD COLUMN window_limit INTEGER NOT NULL DEFAULT 0 CHECK (window_limit >= 0),
+    ADD COLUMN window_days INTEGER NOT NULL DEFAULT 0 CHECK (window_days >= 0),
+    ADD CONSTRAINT merch_window_check CHECK ((window_limit = 0) = (window_days = 0));
+
+-- purchase limits count purchases of item by user
+CREATE INDEX purchases_user_merch_idx ON purchases (user_id, merch_id, created_at);
+DROP INDEX purchases_user_id_idx;
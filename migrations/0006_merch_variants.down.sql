@@ -0,0 +1,13 @@
+ALTER TABLE purchases DROP COLUMN variant_id;
+
+-- variants of item are merged back into one inventory row
+DROP INDEX inventory_user_item_idx;
+WITH merged AS (
+    DELETE FROM inventory RETURNING user_id, merch_id, quantity
+)
+INSERT INTO inventory (user_id, merch_id, quantity)
+SELECT user_id, merch_id, SUM(quantity) FROM merged GROUP BY user_id, merch_id;
+ALTER TABLE inventory DROP COLUMN variant_id;
+ALTER TABLE inventory ADD PRIMARY KEY (user_id, merch_id);
+
+DROP TABLE merch_variants;
@@ -0,0 +1,18 @@
+-- empty size or color means variants of item don't differ in it
+CREATE TABLE merch_variants (
+    id SERIAL PRIMARY KEY,
+    merch_id INTEGER NOT NULL REFERENCES merch(id),
+    size VARCHAR(32) NOT NULL DEFAULT '',
+    color VARCHAR(32) NOT NULL DEFAULT '',
+    price_delta BIGINT NOT NULL DEFAULT 0,
+    stock INTEGER CHECK (stock >= 0),
+    CHECK (size <> '' OR color <> ''),
+    UNIQUE (merch_id, size, color)
+);
+
+-- items bought before variants were added, and items without variants, have NULL variant
+ALTER TABLE inventory ADD COLUMN variant_id INTEGER REFERENCES merch_variants(id);
+ALTER TABLE inventory DROP CONSTRAINT inventory_pkey;
+CREATE UNIQUE INDEX inventory_user_item_idx ON inventory (user_id, merch_id, COALESCE(variant_id, 0));
+
+ALTER TABLE purchases ADD COLUMN variant_id INTEGER REFERENCES merch_variants(id);
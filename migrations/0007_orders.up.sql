@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'employee' CHECK (role IN ('employee', 'manager'));
+
+-- every purchase is one order, so order shares id with its purchase
+CREATE TABLE orders (
+    purchase_id INTEGER PRIMARY KEY REFERENCES purchases(id),
+    status VARCHAR(16) NOT NULL DEFAULT 'placed' CHECK (status IN ('placed', 'packed', 'handed_over', 'cancelled')),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- store managers look for orders that still need work
+CREATE INDEX orders_open_idx ON orders (status) WHERE status IN ('placed', 'packed');
+
+-- items bought before orders existed were given out by hand
+INSERT INTO orders (purchase_id, status, updated_at)
+SELECT id, 'handed_over', created_at FROM purchases;
@@ -0,0 +1,8 @@
+-- purchase is refunded at most once, refund returns its price
+CREATE TABLE refunds (
+    id SERIAL PRIMARY KEY,
+    purchase_id INTEGER NOT NULL UNIQUE REFERENCES purchases(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
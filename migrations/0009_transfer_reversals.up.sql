@@ -0,0 +1,2 @@
+-- reversal is compensating transfer in opposite direction, transfer is reversed at most once
+ALTER TABLE transactions ADD COLUMN reversal_of INTEGER UNIQUE REFERENCES transactions(id);
@@ -0,0 +1 @@
+ALTER TABLE purchases DROP COLUMN recipient_id;
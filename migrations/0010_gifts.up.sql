@@ -0,0 +1,2 @@
+-- gift is purchase paid by user_id, bought item goes to inventory of recipient
+ALTER TABLE purchases ADD COLUMN recipient_id INTEGER REFERENCES users(id) CHECK (recipient_id <> user_id);
@@ -0,0 +1,11 @@
+// Package migrations - versioned SQL migrations of the store database.
+//
+// Every migration consists of two files: NNNN_name.up.sql and NNNN_name.down.sql.
+package migrations
+
+import "embed"
+
+// FS contains all migration files...
+//
+//go:embed *.sql
+var FS embed.FS